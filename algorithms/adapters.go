@@ -0,0 +1,439 @@
+package algorithms
+
+import (
+	"consistent-hash/algorithms/anchor_hash"
+	"consistent-hash/algorithms/dx_hash"
+	"consistent-hash/algorithms/jump_hash"
+	"consistent-hash/algorithms/maglev_hash"
+	"consistent-hash/algorithms/rendezvous_hash"
+	"consistent-hash/algorithms/ring_hash"
+	"consistent-hash/algorithms/slot_hash"
+	"consistent-hash/models"
+	"fmt"
+	"sort"
+)
+
+// getSingleN 只能返回单个节点的算法，GetN 最多返回 1 个节点
+func getSingleN[T models.HashNode](get func(key string) (T, error), key string, n int) ([]T, error) {
+	if n <= 0 {
+		return []T{}, nil
+	}
+	node, err := get(key)
+	if err != nil {
+		return []T{}, err
+	}
+	return []T{node}, nil
+}
+
+// ringHashBalancer RingHash 适配器
+type ringHashBalancer[T models.HashNode] struct {
+	ring *ring_hash.RingHash[T]
+}
+
+func NewRingHashBalancer[T models.HashNode](vnodeBaseNum, ringFloorLimit int, nodes []T,
+	hashFunc func([]byte) uint64) Balancer[T] {
+	return &ringHashBalancer[T]{
+		ring: ring_hash.NewRingHash(vnodeBaseNum, ringFloorLimit, nodes, hashFunc),
+	}
+}
+
+func (r *ringHashBalancer[T]) Get(key string) (T, error) {
+	var zero T
+	results, err := r.ring.Get(key, 1)
+	if err != nil {
+		return zero, err
+	}
+	if len(results) <= 0 {
+		return zero, fmt.Errorf("ring hash get empty result")
+	}
+	return results[0], nil
+}
+
+func (r *ringHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return r.ring.Get(key, n)
+}
+
+func (r *ringHashBalancer[T]) AddNode(node T) error {
+	r.ring.AddNode(node)
+	return nil
+}
+
+func (r *ringHashBalancer[T]) RemoveNode(node T) {
+	r.ring.RemoveNode(node)
+}
+
+func (r *ringHashBalancer[T]) UpdateNode(node T) {
+	r.ring.UpdateNode(node)
+}
+
+func (r *ringHashBalancer[T]) Nodes() []T {
+	return r.ring.GetNodes()
+}
+
+func (r *ringHashBalancer[T]) Len() int {
+	return r.ring.GetNodeCount()
+}
+
+// jumpHashBalancer JumpHash 适配器
+type jumpHashBalancer[T models.HashNode] struct {
+	jump *jump_hash.JumpHash[T]
+}
+
+func NewJumpHashBalancer[T models.HashNode](nodes []T, hashFunc func([]byte) uint64) Balancer[T] {
+	return &jumpHashBalancer[T]{
+		jump: jump_hash.NewJumpHash(nodes, hashFunc),
+	}
+}
+
+// NewWeightedJumpHashBalancer 加权 JumpHash 适配器，节点分到的key的比例与权重成正比
+func NewWeightedJumpHashBalancer[T models.HashNode](nodes []T, hashFunc func([]byte) uint64) Balancer[T] {
+	return &jumpHashBalancer[T]{
+		jump: jump_hash.NewWeightedJumpHash(nodes, hashFunc),
+	}
+}
+
+func (r *jumpHashBalancer[T]) Get(key string) (T, error) {
+	return r.jump.Get(key)
+}
+
+func (r *jumpHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return r.jump.GetN(key, n)
+}
+
+func (r *jumpHashBalancer[T]) AddNode(node T) error {
+	r.jump.AddNode(node)
+	return nil
+}
+
+func (r *jumpHashBalancer[T]) RemoveNode(node T) {
+	r.jump.RemoveNode(node)
+}
+
+func (r *jumpHashBalancer[T]) UpdateNode(node T) {
+	r.jump.UpdateNode(node)
+}
+
+func (r *jumpHashBalancer[T]) Nodes() []T {
+	return r.jump.GetNodes()
+}
+
+func (r *jumpHashBalancer[T]) Len() int {
+	return r.jump.GetNodeCount()
+}
+
+// rendezvousHashBalancer RendezvousHash 适配器
+type rendezvousHashBalancer[T models.HashNode] struct {
+	rendezvous *rendezvous_hash.RendezvousHash[T]
+}
+
+func NewRendezvousHashBalancer[T models.HashNode](nodes []T, hashFunc func([]byte) uint64) Balancer[T] {
+	return &rendezvousHashBalancer[T]{
+		rendezvous: rendezvous_hash.NewRendezvousHash(nodes, hashFunc),
+	}
+}
+
+func (r *rendezvousHashBalancer[T]) Get(key string) (T, error) {
+	return r.rendezvous.Get(key)
+}
+
+func (r *rendezvousHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return r.rendezvous.GetN(key, n)
+}
+
+func (r *rendezvousHashBalancer[T]) AddNode(node T) error {
+	r.rendezvous.AddNode(node)
+	return nil
+}
+
+func (r *rendezvousHashBalancer[T]) RemoveNode(node T) {
+	r.rendezvous.RemoveNode(node)
+}
+
+func (r *rendezvousHashBalancer[T]) UpdateNode(node T) {
+	r.rendezvous.UpdateNode(node)
+}
+
+func (r *rendezvousHashBalancer[T]) Nodes() []T {
+	return r.rendezvous.GetNodes()
+}
+
+func (r *rendezvousHashBalancer[T]) Len() int {
+	return r.rendezvous.GetNodeCount()
+}
+
+// hierarchicalRendezvousHashBalancer 层次化 RendezvousHash 适配器
+type hierarchicalRendezvousHashBalancer[T models.HashNode] struct {
+	rendezvous *rendezvous_hash.HierarchicalRendezvousHash[T]
+}
+
+func NewHierarchicalRendezvousHashBalancer[T models.HashNode](nodes []T, fanout int,
+	hashFunc func([]byte) uint64) Balancer[T] {
+	return &hierarchicalRendezvousHashBalancer[T]{
+		rendezvous: rendezvous_hash.NewHierarchicalRendezvousHash(nodes, fanout, hashFunc),
+	}
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) Get(key string) (T, error) {
+	return r.rendezvous.Get(key)
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return getSingleN(r.rendezvous.Get, key, n)
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) AddNode(node T) error {
+	r.rendezvous.AddNode(node)
+	return nil
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) RemoveNode(node T) {
+	r.rendezvous.RemoveNode(node)
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) UpdateNode(node T) {
+	r.rendezvous.UpdateNode(node)
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) Nodes() []T {
+	return r.rendezvous.GetNodes()
+}
+
+func (r *hierarchicalRendezvousHashBalancer[T]) Len() int {
+	return r.rendezvous.GetNodeCount()
+}
+
+// maglevHashBalancer MaglevHash 适配器
+type maglevHashBalancer[T models.HashNode] struct {
+	maglev *maglev_hash.MaglevHash[T]
+}
+
+// NewMaglevHashBalancer 按参数创建 MaglevHash，opts为空时使用默认参数，TableSize为0时按节点数自动选择查找表大小
+func NewMaglevHashBalancer[T models.HashNode](nodes []T, opts *MaglevHashOptions) (Balancer[T], error) {
+	if opts == nil {
+		opts = DefaultMaglevHashOptions()
+	}
+	maglev, err := maglev_hash.NewMaglevHash(nodes, opts.TableSize, hashFuncOrDefault(opts.HashFunc),
+		secondaryHashFuncOrDefault(opts.SkipHashFunc))
+	if err != nil {
+		return nil, err
+	}
+	maglev.SetMinimalDisruption(opts.MinimalDisruption)
+	maglev.SetHealthAware(opts.HealthAware)
+	return &maglevHashBalancer[T]{
+		maglev: maglev,
+	}, nil
+}
+
+func (r *maglevHashBalancer[T]) Get(key string) (T, error) {
+	return r.maglev.Get(key)
+}
+
+func (r *maglevHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return getSingleN(r.maglev.Get, key, n)
+}
+
+func (r *maglevHashBalancer[T]) AddNode(node T) error {
+	r.maglev.AddNode(node)
+	return nil
+}
+
+func (r *maglevHashBalancer[T]) RemoveNode(node T) {
+	r.maglev.RemoveNode(node)
+}
+
+func (r *maglevHashBalancer[T]) UpdateNode(node T) {
+	r.maglev.UpdateNode(node)
+}
+
+func (r *maglevHashBalancer[T]) Nodes() []T {
+	return r.maglev.GetNodes()
+}
+
+func (r *maglevHashBalancer[T]) Len() int {
+	return r.maglev.GetNodeCount()
+}
+
+// anchorHashBalancer AnchorHash 适配器，AnchorHash 只保存nodeKey，由适配器维护节点实例。
+// AnchorHash 的桶容量在创建时确定，扩容会改变所有key的映射，因此节点数达到容量后 AddNode 返回错误
+type anchorHashBalancer[T models.HashNode] struct {
+	anchor   *anchor_hash.AnchorHash
+	capacity int          // 最大桶容量
+	nodeMap  map[string]T // 节点实例，key为nodeKey
+}
+
+// NewAnchorHashBalancer 创建 AnchorHash 适配器，capacity小于初始节点数时取初始节点数
+func NewAnchorHashBalancer[T models.HashNode](nodes []T, capacity int, hashFunc func([]byte) uint64) Balancer[T] {
+	capacity = max(capacity, len(nodes), 1)
+	r := &anchorHashBalancer[T]{
+		anchor:   anchor_hash.NewAnchorHash(nil, capacity, hashFunc),
+		capacity: capacity,
+		nodeMap:  make(map[string]T),
+	}
+	for _, node := range nodes {
+		r.AddNode(node)
+	}
+	return r
+}
+
+func (r *anchorHashBalancer[T]) Get(key string) (T, error) {
+	var zero T
+	nodeKey, err := r.anchor.Get(key)
+	if err != nil {
+		return zero, err
+	}
+	node, ok := r.nodeMap[nodeKey]
+	if !ok {
+		return zero, fmt.Errorf("anchor hash get unknown node: %v", nodeKey)
+	}
+	return node, nil
+}
+
+func (r *anchorHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return getSingleN(r.Get, key, n)
+}
+
+func (r *anchorHashBalancer[T]) AddNode(node T) error {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; ok {
+		return nil
+	}
+	if r.anchor.GetNodeCount() >= r.capacity {
+		return fmt.Errorf("anchor hash is at capacity %d, can't add node %v", r.capacity, nodeKey)
+	}
+	r.anchor.AddBucket(nodeKey)
+	r.nodeMap[nodeKey] = node
+	return nil
+}
+
+func (r *anchorHashBalancer[T]) RemoveNode(node T) {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; !ok {
+		return
+	}
+	delete(r.nodeMap, nodeKey)
+	r.anchor.RemoveBucket(nodeKey)
+}
+
+func (r *anchorHashBalancer[T]) UpdateNode(node T) {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; !ok {
+		return
+	}
+	r.nodeMap[nodeKey] = node
+}
+
+func (r *anchorHashBalancer[T]) Nodes() []T {
+	nodes := make([]T, 0, len(r.nodeMap))
+	for _, nodeKey := range r.anchor.GetNodes() {
+		nodes = append(nodes, r.nodeMap[nodeKey])
+	}
+	return nodes
+}
+
+func (r *anchorHashBalancer[T]) Len() int {
+	return r.anchor.GetNodeCount()
+}
+
+// dxHashBalancer DxHash 适配器
+type dxHashBalancer[T models.HashNode] struct {
+	dx *dx_hash.DxHash[T]
+}
+
+// NewDxHashBalancer 创建 DxHash 适配器，hashFunc为空时使用MD5
+func NewDxHashBalancer[T models.HashNode](nodes []T, initSize int, hashFunc func([]byte) uint64) Balancer[T] {
+	return &dxHashBalancer[T]{
+		dx: dx_hash.NewDxHash(nodes, initSize, hashFunc),
+	}
+}
+
+func (r *dxHashBalancer[T]) Get(key string) (T, error) {
+	return r.dx.Get(key)
+}
+
+func (r *dxHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	return getSingleN(r.dx.Get, key, n)
+}
+
+func (r *dxHashBalancer[T]) AddNode(node T) error {
+	r.dx.AddNode(node)
+	return nil
+}
+
+func (r *dxHashBalancer[T]) RemoveNode(node T) {
+	r.dx.RemoveNode(node)
+}
+
+func (r *dxHashBalancer[T]) UpdateNode(node T) {
+	r.dx.UpdateNode(node)
+}
+
+func (r *dxHashBalancer[T]) Nodes() []T {
+	return r.dx.GetNodes()
+}
+
+func (r *dxHashBalancer[T]) Len() int {
+	return r.dx.GetNodeCount()
+}
+
+// slotHashBalancer SlotHash 适配器，槽位内的多个节点按nodeKey排序，保证结果稳定
+type slotHashBalancer[T models.HashNode] struct {
+	slot *slot_hash.SlotHash[T]
+}
+
+func NewSlotHashBalancer[T models.HashNode](nodes []T, hashFunc func([]byte) uint64) Balancer[T] {
+	return &slotHashBalancer[T]{
+		slot: slot_hash.NewSlotHash(nodes, hashFunc),
+	}
+}
+
+func (r *slotHashBalancer[T]) Get(key string) (T, error) {
+	var zero T
+	results := r.sortedSlotNodes(key)
+	if len(results) <= 0 {
+		return zero, fmt.Errorf("slot hash get empty result")
+	}
+	return results[0], nil
+}
+
+func (r *slotHashBalancer[T]) GetN(key string, n int) ([]T, error) {
+	if n <= 0 {
+		return []T{}, nil
+	}
+	results := r.sortedSlotNodes(key)
+	if len(results) <= 0 {
+		return []T{}, fmt.Errorf("slot hash get empty result")
+	}
+	if len(results) > n {
+		results = results[:n]
+	}
+	return results, nil
+}
+
+func (r *slotHashBalancer[T]) sortedSlotNodes(key string) []T {
+	results := r.slot.Get(key)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].GetKey() < results[j].GetKey()
+	})
+	return results
+}
+
+func (r *slotHashBalancer[T]) AddNode(node T) error {
+	r.slot.AddNode(node)
+	return nil
+}
+
+func (r *slotHashBalancer[T]) RemoveNode(node T) {
+	r.slot.HardRemoveNode(node.GetKey())
+}
+
+func (r *slotHashBalancer[T]) UpdateNode(node T) {
+	r.slot.UpdateNode(node)
+}
+
+func (r *slotHashBalancer[T]) Nodes() []T {
+	return r.slot.GetNodes()
+}
+
+func (r *slotHashBalancer[T]) Len() int {
+	return r.slot.GetNodeCount()
+}
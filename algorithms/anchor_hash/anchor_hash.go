@@ -35,7 +35,7 @@ func NewAnchorHash(nodeList []string, size int, hashFunc func([]byte) uint64) *A
 		r.K[i] = i
 		r.W[i] = i
 		r.L[i] = i
-		r.A[i] = uint32(size) // 初始化所有桶为移除状态
+		r.A[i] = i // 初始化所有桶为移除状态，A[b]为桶b被移除时的工作集大小
 	}
 	for i := size - 1; i >= 0; i-- {
 		r.R = append(r.R, uint32(i))
@@ -171,3 +171,17 @@ func (r *AnchorHash) Print() {
 	}
 	fmt.Println()
 }
+
+func (r *AnchorHash) GetNodes() []string {
+	nodes := make([]string, 0, r.N)
+	for _, nk := range r.nodeList {
+		if nk != "" {
+			nodes = append(nodes, nk)
+		}
+	}
+	return nodes
+}
+
+func (r *AnchorHash) GetNodeCount() int {
+	return int(r.N)
+}
@@ -0,0 +1,46 @@
+package anchor_hash
+
+import (
+	"consistent-hash/utils"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestAnchorHash_Distribution(t *testing.T) {
+	nodeList := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		nodeList = append(nodeList, "node_"+strconv.Itoa(i))
+	}
+	// 容量大于节点数时，未使用的桶也必须正确初始化为移除状态
+	obj := NewAnchorHash(nodeList, 1000, utils.GetHashCode)
+	keyCount := 200000
+	counts := make(map[string]int)
+	before := make([]string, keyCount)
+	for i := range before {
+		nodeKey, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("anchor hash err: %v", err)
+		}
+		counts[nodeKey]++
+		before[i] = nodeKey
+	}
+	expected := float64(keyCount) / 100
+	for _, nodeKey := range nodeList {
+		if math.Abs(float64(counts[nodeKey])-expected) > expected*0.1 {
+			t.Fatalf("node %v got %v keys, want about %v", nodeKey, counts[nodeKey], expected)
+		}
+	}
+
+	// 删除节点后，只有被删除节点上的key发生迁移
+	obj.RemoveBucket("node_7")
+	for i, nodeKey := range before {
+		current, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil || current == "" || current == "node_7" {
+			t.Fatalf("key_%v got %q, err: %v", i, current, err)
+		}
+		if nodeKey != "node_7" && current != nodeKey {
+			t.Fatalf("key_%v moved from %v to %v", i, nodeKey, current)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package algorithms
+
+import (
+	"consistent-hash/models"
+)
+
+// Balancer 各一致性哈希算法的统一接口，业务可通过配置切换算法而无需修改代码
+type Balancer[T models.HashNode] interface {
+	// Get 获取key对应的节点
+	Get(key string) (T, error)
+	// GetN 获取key对应的至多n个不重复节点，第一个节点与Get的结果一致
+	GetN(key string, n int) ([]T, error)
+	// AddNode 添加节点，节点已存在时忽略；算法的容量已满等原因无法添加时返回错误
+	AddNode(node T) error
+	// RemoveNode 移除节点，节点不存在时忽略
+	RemoveNode(node T)
+	// UpdateNode 更新节点的实例或权重，节点不存在时忽略
+	UpdateNode(node T)
+	// Nodes 返回当前所有节点
+	Nodes() []T
+	// Len 返回当前节点数量
+	Len() int
+}
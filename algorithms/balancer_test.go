@@ -0,0 +1,122 @@
+package algorithms
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"fmt"
+	"testing"
+)
+
+// mustBalancer 创建失败时panic，用于参数固定的测试实例
+func mustBalancer(balancer Balancer[models.HashNode], err error) Balancer[models.HashNode] {
+	if err != nil {
+		panic(err)
+	}
+	return balancer
+}
+
+func newTestBalancers(nodes []models.HashNode) map[string]Balancer[models.HashNode] {
+	return map[string]Balancer[models.HashNode]{
+		"ring":                    NewRingHashBalancer(2, 1, nodes, utils.GetHashCode),
+		"jump":                    NewJumpHashBalancer(nodes, utils.GetHashCode),
+		"weighted_jump":           NewWeightedJumpHashBalancer(nodes, utils.GetHashCode),
+		"rendezvous":              NewRendezvousHashBalancer(nodes, utils.GetHashCode),
+		"hierarchical_rendezvous": NewHierarchicalRendezvousHashBalancer(nodes, 4, utils.GetHashCode),
+		"maglev":                  mustBalancer(NewMaglevHashBalancer(nodes, &MaglevHashOptions{TableSize: 2039})),
+		"maglev_auto":             mustBalancer(NewMaglevHashBalancer(nodes, &MaglevHashOptions{TableSize: 0})),
+		"maglev_minimal":          mustBalancer(NewMaglevHashBalancer(nodes, &MaglevHashOptions{TableSize: 2039, MinimalDisruption: true})),
+		"anchor":                  NewAnchorHashBalancer(nodes, 100, utils.GetHashCode),
+		"dx":                      NewDxHashBalancer(nodes, len(nodes), nil),
+		"slot":                    NewSlotHashBalancer(nodes, utils.GetHashCode),
+	}
+}
+
+func TestBalancer_Adapters(t *testing.T) {
+	// SlotHash 按千分比计算槽位配额，权重取100使所有槽位都有节点
+	nodeCount := 10
+	nodes := make([]models.HashNode, 0, nodeCount)
+	for i := 0; i < nodeCount; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 100, true))
+	}
+	for name, balancer := range newTestBalancers(nodes) {
+		if balancer.Len() != nodeCount || len(balancer.Nodes()) != nodeCount {
+			t.Fatalf("%s: node count %v, want %v", name, balancer.Len(), nodeCount)
+		}
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key_%d", i)
+			node, err := balancer.Get(key)
+			if err != nil {
+				t.Fatalf("%s: get err: %v", name, err)
+			}
+			results, err := balancer.GetN(key, 1)
+			if err != nil {
+				t.Fatalf("%s: getN err: %v", name, err)
+			}
+			if len(results) != 1 || results[0].GetKey() != node.GetKey() {
+				t.Fatalf("%s: getN result %v differs from get result %v", name, results, node.GetKey())
+			}
+		}
+
+		// 添加与删除节点
+		newNode := models.NewNormalHashNode("node_new", 100, true)
+		for i := 0; i < 2; i++ {
+			if err := balancer.AddNode(newNode); err != nil {
+				t.Fatalf("%s: add node err: %v", name, err)
+			}
+		}
+		if balancer.Len() != nodeCount+1 {
+			t.Fatalf("%s: node count after add %v, want %v", name, balancer.Len(), nodeCount+1)
+		}
+		balancer.UpdateNode(models.NewNormalHashNode("node_new", 200, true))
+		if balancer.Len() != nodeCount+1 {
+			t.Fatalf("%s: node count after update %v, want %v", name, balancer.Len(), nodeCount+1)
+		}
+		balancer.RemoveNode(newNode)
+		if balancer.Len() != nodeCount {
+			t.Fatalf("%s: node count after remove %v, want %v", name, balancer.Len(), nodeCount)
+		}
+		for i := 0; i < 100; i++ {
+			node, err := balancer.Get(fmt.Sprintf("key_%d", i))
+			if err != nil {
+				t.Fatalf("%s: get err: %v", name, err)
+			}
+			if node.GetKey() == newNode.GetKey() {
+				t.Fatalf("%s: get removed node", name)
+			}
+		}
+	}
+}
+
+func TestBalancer_AnchorHashCapacity(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 1, true),
+	}
+	// 容量小于初始节点数时取初始节点数
+	balancer := NewAnchorHashBalancer(nodes, 1, utils.GetHashCode)
+	if balancer.Len() != 2 {
+		t.Fatalf("node count %v, want 2", balancer.Len())
+	}
+	// 容量已满时添加节点返回错误，节点不会被加入
+	extra := models.NewNormalHashNode("node_3", 1, true)
+	if err := balancer.AddNode(extra); err == nil {
+		t.Fatalf("add node beyond capacity should return error")
+	}
+	if balancer.Len() != 2 || len(balancer.Nodes()) != 2 {
+		t.Fatalf("node count %v after failed add, want 2", balancer.Len())
+	}
+	// 删除节点后空出的桶可以再次使用
+	balancer.RemoveNode(nodes[0])
+	if err := balancer.AddNode(extra); err != nil {
+		t.Fatalf("add node err: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		node, err := balancer.Get(fmt.Sprintf("key_%d", i))
+		if err != nil {
+			t.Fatalf("get err: %v", err)
+		}
+		if node.GetKey() == nodes[0].GetKey() {
+			t.Fatalf("key_%d got removed node", i)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package algorithms
+
+import (
+	"consistent-hash/models"
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// 批量查询，适用于迁移前比较新旧拓扑等需要解析大量key的场景。
+// 查询期间多个协程并发调用 Balancer.Get，调用方需保证此时不修改 Balancer 的节点
+
+const bulkBatchSize = 1024 // 每个协程一次处理的key数量
+
+// KeyResult 单个key的查询结果
+type KeyResult[T models.HashNode] struct {
+	Key  string
+	Node T
+	Err  error
+}
+
+// workerCount workers不大于0时使用 GOMAXPROCS 个协程
+func workerCount(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// resolve 查询一批key，结果写入results
+func resolve[T models.HashNode](b Balancer[T], keys []string, results []KeyResult[T]) {
+	for i, key := range keys {
+		node, err := b.Get(key)
+		results[i] = KeyResult[T]{Key: key, Node: node, Err: err}
+	}
+}
+
+// GetMany 并发查询一批key，结果与keys的顺序一致
+func GetMany[T models.HashNode](b Balancer[T], keys []string, workers int) []KeyResult[T] {
+	results := make([]KeyResult[T], len(keys))
+	workers = min(workerCount(workers), (len(keys)+bulkBatchSize-1)/bulkBatchSize)
+	// 按批次动态分配，避免某个协程分到的key查询较慢时拖慢整体
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				from := int(next.Add(bulkBatchSize)) - bulkBatchSize
+				if from >= len(keys) {
+					return
+				}
+				to := min(from+bulkBatchSize, len(keys))
+				resolve(b, keys[from:to], results[from:to])
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// bulkBatch 流式查询中的一批key及其结果
+type bulkBatch[T models.HashNode] struct {
+	keys    []string
+	results []KeyResult[T]
+	done    chan struct{}
+}
+
+// GetStream 并发查询从keys中读取的key，按读取的顺序输出结果，适用于无法一次放入内存的输入。
+// keys关闭且所有结果输出后关闭返回的通道；ctx取消后停止读取并关闭返回的通道。
+// 同时处理的批次数量有上限，调用方读取结果较慢时会阻塞读取keys
+func GetStream[T models.HashNode](ctx context.Context, b Balancer[T], keys <-chan string, workers int) <-chan KeyResult[T] {
+	workers = workerCount(workers)
+	out := make(chan KeyResult[T], bulkBatchSize)
+	jobs := make(chan *bulkBatch[T], workers)
+	// 按读取顺序排列的批次，容量限制了同时处理的批次数量
+	pending := make(chan *bulkBatch[T], 2*workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				batch.results = make([]KeyResult[T], len(batch.keys))
+				resolve(b, batch.keys, batch.results)
+				close(batch.done)
+			}
+		}()
+	}
+
+	// 读取key并分批，输入暂时没有新的key时立即提交当前批次，避免结果长时间滞留
+	go func() {
+		defer func() {
+			close(jobs)
+			close(pending)
+		}()
+		for {
+			batchKeys, ok := readBatch(ctx, keys)
+			if len(batchKeys) <= 0 {
+				return
+			}
+			batch := &bulkBatch[T]{keys: batchKeys, done: make(chan struct{})}
+			select {
+			case pending <- batch:
+			case <-ctx.Done():
+				return
+			}
+			jobs <- batch
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	// 按顺序输出每个批次的结果
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		for batch := range pending {
+			select {
+			case <-batch.done:
+			case <-ctx.Done():
+				return
+			}
+			for _, result := range batch.results {
+				select {
+				case out <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// readBatch 读取一批key，keys关闭或ctx取消时ok为false
+func readBatch(ctx context.Context, keys <-chan string) ([]string, bool) {
+	batchKeys := make([]string, 0, bulkBatchSize)
+	// 至少等待一个key
+	select {
+	case key, ok := <-keys:
+		if !ok {
+			return nil, false
+		}
+		batchKeys = append(batchKeys, key)
+	case <-ctx.Done():
+		return nil, false
+	}
+	for len(batchKeys) < bulkBatchSize {
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return batchKeys, false
+			}
+			batchKeys = append(batchKeys, key)
+		default:
+			return batchKeys, true
+		}
+	}
+	return batchKeys, true
+}
@@ -0,0 +1,86 @@
+package algorithms
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestBulk_GetMany(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 100, true))
+	}
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+	for name, balancer := range newTestBalancers(nodes) {
+		results := GetMany(balancer, keys, 4)
+		if len(results) != len(keys) {
+			t.Fatalf("%s: got %v results, want %v", name, len(results), len(keys))
+		}
+		// 结果与keys的顺序一致，且与逐个查询的结果相同
+		for i, result := range results {
+			node, err := balancer.Get(keys[i])
+			if result.Key != keys[i] || result.Err != err || result.Node.GetKey() != node.GetKey() {
+				t.Fatalf("%s: result %v is %+v, want %v", name, i, result, node.GetKey())
+			}
+		}
+	}
+	empty := NewJumpHashBalancer([]models.HashNode{}, utils.GetHashCode)
+	if results := GetMany(empty, keys[:10], 0); len(results) != 10 || results[0].Err == nil {
+		t.Fatalf("empty balancer should return errors")
+	}
+}
+
+func TestBulk_GetStream(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 1, true))
+	}
+	balancer := NewRingHashBalancer(160, 1, nodes, utils.GetHashCode)
+	keyCount := 5000
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		for i := 0; i < keyCount; i++ {
+			keys <- fmt.Sprintf("key_%d", i)
+		}
+	}()
+	// 按读取的顺序输出结果
+	count := 0
+	for result := range GetStream(context.Background(), balancer, keys, 4) {
+		key := fmt.Sprintf("key_%d", count)
+		node, _ := balancer.Get(key)
+		if result.Key != key || result.Err != nil || result.Node.GetKey() != node.GetKey() {
+			t.Fatalf("result %v is %+v, want %v", count, result, node.GetKey())
+		}
+		count++
+	}
+	if count != keyCount {
+		t.Fatalf("got %v results, want %v", count, keyCount)
+	}
+
+	// 取消后停止读取并关闭输出
+	ctx, cancel := context.WithCancel(context.Background())
+	endless := make(chan string)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case endless <- fmt.Sprintf("key_%d", i):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	out := GetStream(ctx, balancer, endless, 2)
+	for i := 0; i < 100; i++ {
+		<-out
+	}
+	cancel()
+	for range out {
+	}
+}
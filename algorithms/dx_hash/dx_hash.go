@@ -3,19 +3,25 @@ package dx_hash
 import (
 	"consistent-hash/models"
 	"crypto/md5"
+	"encoding/binary"
 	"fmt"
 )
 
 type DxHash[T models.HashNode] struct {
-	nodeKeyList    []string     // 节点列表
-	nodeMap        map[string]T // 节点映射，key为nodeKey
-	nsTable        []int        // NSArray查找表
-	nsSize         int          // 当前NSArray的大小
-	nodeCount      int          // 当前节点数量
-	availableStack []int        // 栈，可用位置，用于优化节点添加/删除
+	nodeKeyList    []string            // 节点列表
+	nodeMap        map[string]T        // 节点映射，key为nodeKey
+	nsTable        []int               // NSArray查找表
+	nsSize         int                 // 当前NSArray的大小
+	nodeCount      int                 // 当前节点数量
+	availableStack []int               // 栈，可用位置，用于优化节点添加/删除
+	hashFunc       func([]byte) uint64 // 哈希函数，输入为key加探测序号
 }
 
-func NewDxHash[T models.HashNode](nodeList []T, initSize int) *DxHash[T] {
+// NewDxHash 创建 DxHash，hashFunc为空时使用MD5
+func NewDxHash[T models.HashNode](nodeList []T, initSize int, hashFunc func([]byte) uint64) *DxHash[T] {
+	if hashFunc == nil {
+		hashFunc = md5Hash
+	}
 	// 初始大小确保是2的幂
 	size := 1
 	for size < initSize {
@@ -28,6 +34,7 @@ func NewDxHash[T models.HashNode](nodeList []T, initSize int) *DxHash[T] {
 		nsSize:         size,
 		nodeCount:      0,
 		availableStack: make([]int, 0, size),
+		hashFunc:       hashFunc,
 	}
 	// 初始化可用位置栈
 	for i := 0; i < size; i++ {
@@ -41,11 +48,14 @@ func NewDxHash[T models.HashNode](nodeList []T, initSize int) *DxHash[T] {
 }
 
 func (r *DxHash[T]) hash(key string, seed int) uint64 {
-	h := md5.Sum([]byte(key + string(rune(seed))))
-	// 确保结果为正数
-	result := (uint64(h[0]) << 56) | (uint64(h[1]) << 48) | (uint64(h[2]) << 40) | (uint64(h[3]) << 32) |
-		(uint64(h[4]) << 24) | (uint64(h[5]) << 16) | (uint64(h[6]) << 8) | uint64(h[7])
-	return result & 0x7fffffffffffffff // 清除符号位确保为正数
+	// 清除符号位确保转为int后为正数
+	return r.hashFunc([]byte(key+string(rune(seed)))) & 0x7fffffffffffffff
+}
+
+// md5Hash 默认哈希函数，取MD5的前8字节
+func md5Hash(data []byte) uint64 {
+	h := md5.Sum(data)
+	return binary.BigEndian.Uint64(h[:8])
 }
 
 func (r *DxHash[T]) resize() {
@@ -111,7 +121,8 @@ func (r *DxHash[T]) RemoveNode(node T) {
 	}
 	delete(r.nodeMap, nodeKey)
 	index := -1
-	for i := 0; i < r.nodeCount; i++ {
+	// 可用位置从栈顶分配，节点所在的位置可能不小于nodeCount
+	for i := range r.nodeKeyList {
 		if r.nodeKeyList[i] == nodeKey {
 			index = i
 			break
@@ -120,9 +131,12 @@ func (r *DxHash[T]) RemoveNode(node T) {
 	if index == -1 {
 		return
 	}
-	// 从NSArray中移除节点的位置
+	r.nodeKeyList[index] = ""
+	// 从NSArray中移除节点的位置，该位置已被其他节点覆盖时保留
 	pos := int(r.hash(nodeKey, 0)) % r.nsSize
-	r.nsTable[pos] = -1
+	if r.nsTable[pos] == index {
+		r.nsTable[pos] = -1
+	}
 	// 将位置放回可用栈
 	r.availableStack = append(r.availableStack, index)
 	// 减少节点技术
@@ -169,3 +183,27 @@ func (r *DxHash[T]) GetNodeCount() int {
 func (r *DxHash[T]) GetTableSize() int {
 	return r.nsSize
 }
+
+// UpdateNode 更新节点实例，节点在NSArray中的位置不变
+func (r *DxHash[T]) UpdateNode(node T) {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; !ok {
+		return
+	}
+	r.nodeMap[nodeKey] = node
+}
+
+func (r *DxHash[T]) GetNodes() []T {
+	nodes := make([]T, 0, len(r.nodeMap))
+	seen := make(map[string]struct{}, len(r.nodeMap))
+	for _, nk := range r.nodeKeyList {
+		if _, ok := seen[nk]; ok {
+			continue
+		}
+		if node, ok := r.nodeMap[nk]; ok {
+			seen[nk] = struct{}{}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,43 @@
+package dx_hash
+
+import (
+	"consistent-hash/models"
+	"strconv"
+	"testing"
+)
+
+func TestDxHash_RemoveNode(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 20)
+	for i := 0; i < 20; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewDxHash(nodes, 64, nil)
+	keyCount := 20000
+	before := make([]string, keyCount)
+	for i := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("dx hash err: %v", err)
+		}
+		before[i] = node.GetKey()
+	}
+	// 删除节点后节点数减少，只有被删除节点上的key发生迁移
+	obj.RemoveNode(nodes[5])
+	if obj.GetNodeCount() != 19 {
+		t.Fatalf("node count %v after remove, want 19", obj.GetNodeCount())
+	}
+	for i, nodeKey := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("dx hash err: %v", err)
+		}
+		if node.GetKey() == "node_5" || (nodeKey != "node_5" && node.GetKey() != nodeKey) {
+			t.Fatalf("key_%v moved from %v to %v", i, nodeKey, node.GetKey())
+		}
+	}
+	// 被删除节点的位置可以被重新使用
+	obj.AddNode(nodes[5])
+	if obj.GetNodeCount() != 20 {
+		t.Fatalf("node count %v after re-add, want 20", obj.GetNodeCount())
+	}
+}
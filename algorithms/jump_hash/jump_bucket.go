@@ -0,0 +1,62 @@
+package jump_hash
+
+import "math/bits"
+
+// 与 Guava Hashing.consistentHash 逐位兼容的跳跃哈希 (Lamping, Veach, 2014)
+// Guava 的实现:
+//
+//	state = 2862933555777941757 * state + 1
+//	nextDouble = (double)((int)(state >>> 33) + 1) / 2^31
+//	next = (int)((candidate + 1) / nextDouble)
+//
+// 这里只使用整数运算复现上述浮点运算的结果:
+// 1. (candidate+1) / nextDouble 即 (candidate+1)*2^31 / (x+1)，被除数与除数都能被 double 精确表示，
+//    因此结果是真实商按 IEEE 754 就近舍入后的值，再向零取整
+// 2. 真实商 q0 + rem/d 的小数部分足够接近 1 时会被舍入为 q0+1，按 double 的精度判断即可
+// 3. x 为 2^31-1 时 Java 的 int 加法溢出为 -2^31，next 为负数，Guava 直接返回当前的候选桶。
+//    论文的 C++ 实现使用 64 位整数，不会溢出，此时两者结果不同，这里与 Guava 保持一致
+
+const jumpMultiplier = 2862933555777941757 // 线性同余生成器的乘数
+
+// JumpBucket 返回hash对应的桶编号，范围为 [0, buckets)，buckets不大于0时返回-1
+func JumpBucket(hash uint64, buckets int32) int32 {
+	if buckets <= 0 {
+		return -1
+	}
+	state := hash
+	candidate := int64(0)
+	for {
+		state = jumpMultiplier*state + 1
+		x := state >> 33
+		// Java int 溢出，nextDouble 为 -1
+		if x == 1<<31-1 {
+			return int32(candidate)
+		}
+		next := jumpDivide(uint64(candidate+1)<<31, x+1)
+		if next >= int64(buckets) {
+			return int32(candidate)
+		}
+		candidate = next
+	}
+}
+
+// jumpDivide 计算 n/d 按 double 就近舍入后向零取整的结果，d 不超过 2^31，
+// 结果超过 int32 范围时 Java 的类型转换会饱和，调用方只需知道其不小于桶数
+func jumpDivide(n, d uint64) int64 {
+	q0, rem := n/d, n%d
+	if q0 >= 1<<31 {
+		return 1 << 31
+	}
+	if rem == 0 || q0 == 0 {
+		// 商小于 1 时 double 的精度远高于 1/d，不会被舍入为 1
+		return int64(q0)
+	}
+	// 商在 [2^e, 2^(e+1)) 内时 double 的精度为 2^(e-52)，与 q0+1 的距离 (d-rem)/d
+	// 不超过半个精度，即 (d-rem)*2^(53-e) <= d 时舍入为 q0+1 (恰好相等时 q0+1 的尾数为偶数)。
+	// d 不超过 2^31，左移后的位数超过 32 时必然大于 d，同时避免溢出
+	s := 53 - (bits.Len64(q0) - 1)
+	if diff := d - rem; bits.Len64(diff)+s <= 32 && diff<<s <= d {
+		return int64(q0) + 1
+	}
+	return int64(q0)
+}
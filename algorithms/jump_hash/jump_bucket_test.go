@@ -0,0 +1,116 @@
+package jump_hash
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// guavaConsistentHash 按 Guava Hashing.consistentHash 的浮点运算实现，作为对照
+func guavaConsistentHash(input uint64, buckets int32) int32 {
+	state := input
+	candidate := int32(0)
+	for {
+		state = jumpMultiplier*state + 1
+		// Java: (double)((int)(state >>> 33) + 1) / 0x1.0p31，int 加法可能溢出
+		nextDouble := float64(int32(state>>33)+1) / (1 << 31)
+		quotient := float64(candidate+1) / nextDouble
+		// Java 的 (int) 转换在超出范围时饱和
+		var next int32
+		switch {
+		case quotient >= math.MaxInt32:
+			next = math.MaxInt32
+		case quotient <= math.MinInt32:
+			next = math.MinInt32
+		default:
+			next = int32(quotient)
+		}
+		if next >= 0 && next < buckets {
+			candidate = next
+		} else {
+			return candidate
+		}
+	}
+}
+
+func TestJumpBucket_GuavaGoldenVectors(t *testing.T) {
+	// Guava HashingTest.testConsistentHash_linearCongruentialGeneratorCompatibility
+	golden100 := []int32{0, 55, 62, 8, 45, 59, 86, 97, 82, 59, 73, 37, 17, 56, 86, 21, 90, 37, 38, 83}
+	for i, expected := range golden100 {
+		if bucket := JumpBucket(uint64(i), 100); bucket != expected {
+			t.Fatalf("JumpBucket(%v, 100) = %v, want %v", i, bucket, expected)
+		}
+	}
+	cases := []struct {
+		hash     uint64
+		buckets  int32
+		expected int32
+	}{
+		{10863919174838991, 11, 6},
+		{2016238256797177309, 11, 3},
+		{1673758223894951030, 11, 5},
+		{2, 100001, 80343},
+		{2201, 100001, 22152},
+		{2202, 100001, 15018},
+	}
+	for _, c := range cases {
+		if bucket := JumpBucket(c.hash, c.buckets); bucket != c.expected {
+			t.Fatalf("JumpBucket(%v, %v) = %v, want %v", c.hash, c.buckets, bucket, c.expected)
+		}
+	}
+	if JumpBucket(1, 0) != -1 || JumpBucket(1, -1) != -1 {
+		t.Fatalf("non-positive buckets should return -1")
+	}
+}
+
+func TestJumpBucket_MatchFloatReference(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	bucketsList := []int32{1, 2, 7, 100, 100001, 1 << 20, 1 << 30, math.MaxInt32}
+	for i := 0; i < 200000; i++ {
+		hash := rnd.Uint64()
+		buckets := bucketsList[i%len(bucketsList)]
+		if i%3 == 0 {
+			buckets = rnd.Int31()
+		}
+		if got, expected := JumpBucket(hash, buckets), guavaConsistentHash(hash, buckets); got != expected {
+			t.Fatalf("JumpBucket(%v, %v) = %v, want %v", hash, buckets, got, expected)
+		}
+	}
+}
+
+func TestJumpBucket_Rounding(t *testing.T) {
+	// 商略小于整数时 double 会舍入为该整数，逐个比较整数实现与浮点实现。
+	// 被除数 (candidate+1)*2^31 总能被 double 精确表示，构造 n = (q+1)*d - diff，
+	// 其中 d 为奇数，q+1 = diff * d^-1 mod 2^31，使 n 为 2^31 的倍数且商略小于 q+1
+	rnd := rand.New(rand.NewSource(2))
+	roundUp := 0
+	for i := 0; i < 200000; i++ {
+		d := uint64(rnd.Int63n(1<<30))<<1 | 1
+		diff := uint64(rnd.Int63n(8)) + 1
+		inverse := d // 牛顿迭代求 d 在模 2^64 下的逆元
+		for j := 0; j < 5; j++ {
+			inverse *= 2 - d*inverse
+		}
+		q1 := diff * inverse & (1<<31 - 1)
+		n := q1*d - diff
+		if q1 == 0 || n>>31 > 1<<31 {
+			continue
+		}
+		quotient := float64(n) / float64(d)
+		expected := int64(math.MaxInt32) + 1
+		if quotient < float64(expected) {
+			expected = int64(quotient)
+		}
+		got := jumpDivide(n, d)
+		if got != expected {
+			t.Fatalf("jumpDivide(%v, %v) = %v, want %v", n, d, got, expected)
+		}
+		if got == int64(n/d)+1 {
+			roundUp++
+		}
+	}
+	if roundUp <= 0 {
+		t.Fatalf("no rounding case covered")
+	}
+	t.Logf("rounding cases: %v", roundUp)
+}
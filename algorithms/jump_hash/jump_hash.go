@@ -2,77 +2,214 @@ package jump_hash
 
 import (
 	"consistent-hash/models"
+	"consistent-hash/utils"
 	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+const (
+	replicaAttempts = 4                  // GetN 每个副本的平均尝试次数
+	replicaSeedStep = 0x9e3779b97f4a7c15 // 副本序号的播种步长，2^64 除以黄金比例
 )
 
 type JumpHash[T models.HashNode] struct {
-	nodeList []T                 // 节点列表
-	nodeMap  map[string]struct{} // 节点映射，key为nodeKey
-	hashFunc func([]byte) uint64 // 哈希函数
+	nodeList    []T                 // 桶对应的节点，被删除的桶为零值
+	nodeMap     map[string]T        // 节点信息，key为nodeKey
+	nodeBuckets map[string][]int    // 节点占用的桶，key为nodeKey
+	activeKeys  []string            // 占用桶的节点，按nodeKey排序，GetN 补齐副本时按此顺序选取
+	buckets     *memento            // 桶的删除与恢复
+	weighted    bool                // 是否按权重分配桶
+	hashFunc    func([]byte) uint64 // 哈希函数
 }
 
 func NewJumpHash[T models.HashNode](nodeList []T, hashFunc func([]byte) uint64) *JumpHash[T] {
 	obj := &JumpHash[T]{
-		nodeList: make([]T, 0),
-		nodeMap:  make(map[string]struct{}),
-		hashFunc: hashFunc,
+		nodeList:    make([]T, 0),
+		nodeMap:     make(map[string]T),
+		nodeBuckets: make(map[string][]int),
+		buckets:     newMemento(),
+		hashFunc:    hashFunc,
+	}
+	for _, node := range nodeList {
+		obj.AddNode(node)
 	}
+	return obj
+}
+
+// NewWeightedJumpHash 创建加权跳跃哈希，每个节点占用与权重相同数量的桶，
+// 节点分到的key的比例与权重成正比。权重不大于0的节点不占用桶
+func NewWeightedJumpHash[T models.HashNode](nodeList []T, hashFunc func([]byte) uint64) *JumpHash[T] {
+	obj := NewJumpHash([]T{}, hashFunc)
+	obj.weighted = true
 	for _, node := range nodeList {
 		obj.AddNode(node)
 	}
 	return obj
 }
 
-func (r *JumpHash[T]) generateJumpConsistentHash(keyHash uint64, numBuckets int) int {
-	if numBuckets <= 0 {
-		return -1
-	}
-	var b int64 = -1
-	var j int64 = 0
-	for j < int64(numBuckets) {
-		b = j
-		keyHash = keyHash*2862933555777941757 + 1
-		j = int64((float64(b+1) * float64(1<<31)) / float64((keyHash>>33)+1))
-	}
-	return int(b)
+// bucketCount 节点应占用的桶数
+func (r *JumpHash[T]) bucketCount(node T) int {
+	if !r.weighted {
+		return 1
+	}
+	return max(node.GetWeight(), 0)
+}
+
+// addBuckets 为节点添加count个桶，优先恢复最后被删除的桶
+func (r *JumpHash[T]) addBuckets(node T, count int) {
+	nodeKey := node.GetKey()
+	if count > 0 && len(r.nodeBuckets[nodeKey]) <= 0 {
+		idx, _ := slices.BinarySearch(r.activeKeys, nodeKey)
+		r.activeKeys = slices.Insert(r.activeKeys, idx, nodeKey)
+	}
+	for i := 0; i < count; i++ {
+		bucket := r.buckets.add()
+		if bucket == len(r.nodeList) {
+			r.nodeList = append(r.nodeList, node)
+		} else {
+			r.nodeList[bucket] = node
+		}
+		r.nodeBuckets[nodeKey] = append(r.nodeBuckets[nodeKey], bucket)
+	}
+}
+
+// removeBuckets 删除节点最后添加的count个桶
+func (r *JumpHash[T]) removeBuckets(nodeKey string, count int) {
+	var zero T
+	nodeBuckets := r.nodeBuckets[nodeKey]
+	for i := 0; i < count && len(nodeBuckets) > 0; i++ {
+		bucket := nodeBuckets[len(nodeBuckets)-1]
+		nodeBuckets = nodeBuckets[:len(nodeBuckets)-1]
+		r.buckets.remove(bucket)
+		r.nodeList[bucket] = zero
+	}
+	r.nodeBuckets[nodeKey] = nodeBuckets
+	if len(nodeBuckets) <= 0 {
+		if idx, ok := slices.BinarySearch(r.activeKeys, nodeKey); ok {
+			r.activeKeys = slices.Delete(r.activeKeys, idx, idx+1)
+		}
+	}
+	// 桶数组缩小时同步截断
+	r.nodeList = r.nodeList[:r.buckets.size]
 }
 
+// AddNode 添加节点，优先恢复最后被删除的桶
 func (r *JumpHash[T]) AddNode(node T) {
 	nodeKey := node.GetKey()
 	if _, ok := r.nodeMap[nodeKey]; ok {
 		return
 	}
-	r.nodeMap[nodeKey] = struct{}{}
-	r.nodeList = append(r.nodeList, node)
+	r.nodeMap[nodeKey] = node
+	r.addBuckets(node, r.bucketCount(node))
 }
 
+// RemoveNode 删除节点，只有该节点上的key发生迁移
 func (r *JumpHash[T]) RemoveNode(node T) {
 	nodeKey := node.GetKey()
 	if _, ok := r.nodeMap[nodeKey]; !ok {
 		return
 	}
+	r.removeBuckets(nodeKey, len(r.nodeBuckets[nodeKey]))
 	delete(r.nodeMap, nodeKey)
-	for i, n := range r.nodeList {
-		if n.GetKey() == nodeKey {
-			r.nodeList = append(r.nodeList[:i], r.nodeList[i+1:]...)
-			return
-		}
-	}
+	delete(r.nodeBuckets, nodeKey)
 }
 
 func (r *JumpHash[T]) Get(key string) (T, error) {
 	var zero T
-	if len(r.nodeList) <= 0 {
+	if r.buckets.workingCount() <= 0 {
 		return zero, fmt.Errorf("nodeList is empty")
 	}
 	keyHash := r.hashFunc([]byte(key))
-	idx := r.generateJumpConsistentHash(keyHash, len(r.nodeList))
+	idx := r.buckets.lookup(keyHash)
 	if idx < 0 || idx >= len(r.nodeList) {
 		return zero, fmt.Errorf("generate jumpHash idx error")
 	}
 	return r.nodeList[idx], nil
 }
 
+// GetN 获取key对应的至多n个不同节点，第一个节点与 Get 一致。
+// 第a个副本使用重新播种的哈希 Mix64(keyHash + a*黄金比例) 查询，每个序号的查询都是独立的一致性哈希，
+// 节点变化时只有应当迁移的副本发生变化。命中已选节点时继续下一个序号，
+// 尝试次数用尽后从 activeKeys 中由key决定的位置开始依次补齐，最多访问 2n 个节点，保证结果确定
+func (r *JumpHash[T]) GetN(key string, n int) ([]T, error) {
+	if r.buckets.workingCount() <= 0 {
+		return nil, fmt.Errorf("nodeList is empty")
+	}
+	// 只有占用桶的节点能被选中
+	n = min(n, len(r.activeKeys))
+	results := make([]T, 0, max(n, 0))
+	seen := make(map[string]struct{}, max(n, 0))
+	keyHash := r.hashFunc([]byte(key))
+	for a := 0; a < n*replicaAttempts && len(results) < n; a++ {
+		hash := keyHash
+		if a > 0 {
+			hash = utils.Mix64(keyHash + uint64(a)*replicaSeedStep)
+		}
+		node := r.nodeList[r.buckets.lookup(hash)]
+		if _, ok := seen[node.GetKey()]; ok {
+			continue
+		}
+		seen[node.GetKey()] = struct{}{}
+		results = append(results, node)
+	}
+	if len(results) >= n {
+		return results, nil
+	}
+	// 从key决定的位置开始依次补齐剩余的副本，已选中的节点不超过n个
+	start := int(utils.Mix64(keyHash) % uint64(len(r.activeKeys)))
+	for i := 0; len(results) < n; i++ {
+		nodeKey := r.activeKeys[(start+i)%len(r.activeKeys)]
+		if _, ok := seen[nodeKey]; ok {
+			continue
+		}
+		seen[nodeKey] = struct{}{}
+		results = append(results, r.nodeMap[nodeKey])
+	}
+	return results, nil
+}
+
 func (r *JumpHash[T]) GetNodeCount() int {
-	return len(r.nodeList)
+	return len(r.nodeMap)
+}
+
+// UpdateNode 更新节点实例，节点已占用的桶不变；加权模式下权重变化时只增删差值部分的桶
+func (r *JumpHash[T]) UpdateNode(node T) {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; !ok {
+		return
+	}
+	r.nodeMap[nodeKey] = node
+	for _, bucket := range r.nodeBuckets[nodeKey] {
+		r.nodeList[bucket] = node
+	}
+	oldCount, newCount := len(r.nodeBuckets[nodeKey]), r.bucketCount(node)
+	if newCount > oldCount {
+		r.addBuckets(node, newCount-oldCount)
+	} else if newCount < oldCount {
+		r.removeBuckets(nodeKey, oldCount-newCount)
+	}
+}
+
+// GetNodes 按节点第一个桶的顺序返回所有节点，不占用桶的节点排在最后
+func (r *JumpHash[T]) GetNodes() []T {
+	nodes := make([]T, 0, len(r.nodeMap))
+	for _, node := range r.nodeMap {
+		nodes = append(nodes, node)
+	}
+	firstBucket := func(node T) int {
+		if buckets := r.nodeBuckets[node.GetKey()]; len(buckets) > 0 {
+			return buckets[0]
+		}
+		return math.MaxInt
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		bi, bj := firstBucket(nodes[i]), firstBucket(nodes[j])
+		if bi != bj {
+			return bi < bj
+		}
+		return nodes[i].GetKey() < nodes[j].GetKey()
+	})
+	return nodes
 }
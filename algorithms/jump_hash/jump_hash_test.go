@@ -0,0 +1,256 @@
+package jump_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func newTestNodes(count int) []models.HashNode {
+	nodes := make([]models.HashNode, 0, count)
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	return nodes
+}
+
+func getMapping(t *testing.T, obj *JumpHash[models.HashNode], keyCount int) map[string]string {
+	mapping := make(map[string]string, keyCount)
+	for i := 0; i < keyCount; i++ {
+		key := "key_" + strconv.Itoa(i)
+		node, err := obj.Get(key)
+		if err != nil {
+			t.Fatalf("jump hash err: %v", err)
+		}
+		mapping[key] = node.GetKey()
+	}
+	return mapping
+}
+
+func TestJumpHash_RemoveMiddleNode(t *testing.T) {
+	nodes := newTestNodes(10)
+	obj := NewJumpHash(nodes, utils.GetHashCode)
+	before := getMapping(t, obj, 100000)
+
+	// 删除中间的节点，只有该节点上的key发生迁移
+	removed := map[string]struct{}{}
+	for _, idx := range []int{3, 7, 0} {
+		obj.RemoveNode(nodes[idx])
+		removed[nodes[idx].GetKey()] = struct{}{}
+		after := getMapping(t, obj, 100000)
+		counts := make(map[string]int)
+		for key, nodeKey := range after {
+			if _, ok := removed[nodeKey]; ok {
+				t.Fatalf("key %v got removed node %v", key, nodeKey)
+			}
+			if _, ok := removed[before[key]]; !ok && before[key] != nodeKey {
+				t.Fatalf("key %v moved from %v to %v", key, before[key], nodeKey)
+			}
+			counts[nodeKey]++
+		}
+		// 迁移的key均匀分布在剩余节点上
+		expected := 100000.0 / float64(obj.GetNodeCount())
+		for nodeKey, count := range counts {
+			if math.Abs(float64(count)-expected) > expected*0.05 {
+				t.Fatalf("node %v got %v keys, want about %v", nodeKey, count, expected)
+			}
+		}
+		before = after
+	}
+	if obj.GetNodeCount() != 7 || len(obj.GetNodes()) != 7 {
+		t.Fatalf("node count %v, want 7", obj.GetNodeCount())
+	}
+}
+
+func TestJumpHash_RestoreNode(t *testing.T) {
+	nodes := newTestNodes(10)
+	obj := NewJumpHash(nodes, utils.GetHashCode)
+	original := getMapping(t, obj, 50000)
+	obj.RemoveNode(nodes[2])
+	obj.RemoveNode(nodes[5])
+	obj.RemoveNode(nodes[9])
+	// 按删除的逆序恢复后，key的映射与删除前一致
+	obj.AddNode(nodes[9])
+	obj.AddNode(nodes[5])
+	obj.AddNode(nodes[2])
+	restored := getMapping(t, obj, 50000)
+	for key, nodeKey := range original {
+		if restored[key] != nodeKey {
+			t.Fatalf("key %v mapped to %v, want %v", key, restored[key], nodeKey)
+		}
+	}
+
+	// 替换表为空时删除最后一个桶，桶数组直接缩小
+	obj.RemoveNode(nodes[9])
+	if len(obj.buckets.replacements) != 0 || obj.buckets.size != 9 {
+		t.Fatalf("buckets size %v, replacements %v", obj.buckets.size, len(obj.buckets.replacements))
+	}
+	obj.AddNode(models.NewNormalHashNode("node_new", 1, true))
+	if obj.buckets.size != 10 || obj.GetNodeCount() != 10 {
+		t.Fatalf("buckets size %v, node count %v", obj.buckets.size, obj.GetNodeCount())
+	}
+
+	// 删除所有节点
+	for _, node := range obj.GetNodes() {
+		obj.RemoveNode(node)
+	}
+	if _, err := obj.Get("key"); err == nil {
+		t.Fatalf("empty jump hash should return error")
+	}
+}
+
+func TestJumpHash_Weighted(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 2, true),
+		models.NewNormalHashNode("node_3", 3, true),
+		models.NewNormalHashNode("node_4", 4, true),
+	}
+	obj := NewWeightedJumpHash(nodes, utils.GetHashCode)
+	checkShare := func(totalWeight int) map[string]string {
+		mapping := getMapping(t, obj, 200000)
+		counts := make(map[string]int)
+		for _, nodeKey := range mapping {
+			counts[nodeKey]++
+		}
+		// 节点分到的key的比例与权重成正比
+		for _, node := range obj.GetNodes() {
+			expected := 200000 * float64(node.GetWeight()) / float64(totalWeight)
+			if math.Abs(float64(counts[node.GetKey()])-expected) > expected*0.05 {
+				t.Fatalf("node %v got %v keys, want about %v", node.GetKey(), counts[node.GetKey()], expected)
+			}
+		}
+		return mapping
+	}
+	before := checkShare(10)
+
+	// 增加权重后，只有迁移到该节点的key发生变化
+	nodes[0].SetWeight(5)
+	obj.UpdateNode(nodes[0])
+	after := checkShare(14)
+	for key, nodeKey := range after {
+		if nodeKey != before[key] && nodeKey != "node_1" {
+			t.Fatalf("key %v moved from %v to %v", key, before[key], nodeKey)
+		}
+	}
+
+	// 删除节点后，只有该节点上的key发生迁移
+	obj.RemoveNode(nodes[2])
+	before = after
+	after = checkShare(11)
+	for key, nodeKey := range after {
+		if nodeKey != before[key] && before[key] != "node_3" {
+			t.Fatalf("key %v moved from %v to %v", key, before[key], nodeKey)
+		}
+	}
+}
+
+func TestJumpHash_GetN(t *testing.T) {
+	nodes := newTestNodes(10)
+	obj := NewJumpHash(nodes, utils.GetHashCode)
+	replicas := 3
+	before := make(map[string][]models.HashNode)
+	for i := 0; i < 20000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, err := obj.GetN(key, replicas)
+		if err != nil {
+			t.Fatalf("jump hash err: %v", err)
+		}
+		if len(results) != replicas {
+			t.Fatalf("key %v got %v nodes, want %v", key, len(results), replicas)
+		}
+		// 第一个副本与 Get 一致，副本之间互不相同
+		primary, _ := obj.Get(key)
+		if results[0].GetKey() != primary.GetKey() {
+			t.Fatalf("key %v primary %v, want %v", key, results[0].GetKey(), primary.GetKey())
+		}
+		seen := make(map[string]struct{})
+		for _, node := range results {
+			seen[node.GetKey()] = struct{}{}
+		}
+		if len(seen) != replicas {
+			t.Fatalf("key %v got duplicate replicas", key)
+		}
+		before[key] = results
+	}
+	// 超过节点数时返回所有节点
+	results, _ := obj.GetN("key", 20)
+	if len(results) != len(nodes) {
+		t.Fatalf("got %v nodes, want %v", len(results), len(nodes))
+	}
+
+	// 添加节点后，副本发生变化的key都包含新节点，且比例约为 replicas/(N+1)
+	obj.AddNode(models.NewNormalHashNode("node_new", 1, true))
+	changed := 0
+	for key, old := range before {
+		results, _ := obj.GetN(key, replicas)
+		oldSet := make(map[string]struct{})
+		for _, node := range old {
+			oldSet[node.GetKey()] = struct{}{}
+		}
+		diff, hasNew := false, false
+		for _, node := range results {
+			if node.GetKey() == "node_new" {
+				hasNew = true
+			} else if _, ok := oldSet[node.GetKey()]; !ok {
+				diff = true
+			}
+		}
+		if diff && !hasNew {
+			t.Fatalf("key %v replicas changed without the new node", key)
+		}
+		if hasNew {
+			changed++
+		}
+	}
+	expected := 20000.0 * float64(replicas) / float64(len(nodes)+1)
+	if math.Abs(float64(changed)-expected) > expected*0.1 {
+		t.Fatalf("%v keys changed, want about %v", changed, expected)
+	}
+}
+
+func TestJumpHash_GetNFallback(t *testing.T) {
+	// 权重悬殊时尝试次数经常用尽，由 activeKeys 补齐；权重为0的节点不占用桶，不会被选中
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_0", 100, true),
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 1, true),
+		models.NewNormalHashNode("node_3", 1, true),
+		models.NewNormalHashNode("node_4", 0, true),
+	}
+	obj := NewWeightedJumpHash(nodes, utils.GetHashCode)
+	for i := 0; i < 1000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, err := obj.GetN(key, 5)
+		if err != nil {
+			t.Fatalf("jump hash err: %v", err)
+		}
+		if len(results) != 4 {
+			t.Fatalf("key %v got %v nodes, want 4", key, len(results))
+		}
+		seen := make(map[string]struct{})
+		for _, node := range results {
+			if node.GetKey() == "node_4" {
+				t.Fatalf("key %v got node without buckets", key)
+			}
+			seen[node.GetKey()] = struct{}{}
+		}
+		if len(seen) != 4 {
+			t.Fatalf("key %v got duplicate replicas", key)
+		}
+		again, _ := obj.GetN(key, 5)
+		for j := range results {
+			if again[j].GetKey() != results[j].GetKey() {
+				t.Fatalf("key %v replicas not deterministic", key)
+			}
+		}
+	}
+	// 节点不再占用桶后不会被补齐选中
+	obj.UpdateNode(models.NewNormalHashNode("node_3", 0, true))
+	obj.RemoveNode(nodes[2])
+	if results, _ := obj.GetN("key", 5); len(results) != 2 {
+		t.Fatalf("got %v nodes, want 2", len(results))
+	}
+}
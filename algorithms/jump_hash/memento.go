@@ -0,0 +1,80 @@
+package jump_hash
+
+import "consistent-hash/utils"
+
+// MementoHash (Coluzzi, Brocco, Antonucci, Leidi, 2023)
+// 跳跃哈希只支持删除最后一个桶。MementoHash 用替换表记录被删除的桶:
+// 1. 删除桶b时记录 b -> (当时的工作桶数-1, 上一个被删除的桶)，工作桶数减1
+// 2. 查询时先用跳跃哈希得到桶b，若b已被删除，则用key和b重新哈希到 [0, 替换值) 中，
+//    并沿着更早删除的桶的替换链找到工作桶，因此只有被删除桶上的key发生迁移
+// 3. 添加桶时按删除的逆序恢复最后一个被删除的桶；没有被删除的桶时在末尾追加
+// 4. 删除最后一个桶且替换表为空时直接缩小桶数组，与原始跳跃哈希一致
+
+// replacement 被删除桶的替换信息
+type replacement struct {
+	replacer int // 删除时的工作桶数-1
+	prev     int // 在此之前最后被删除的桶
+}
+
+type memento struct {
+	size         int                 // 桶数组的大小，包括被删除的桶
+	lastRemoved  int                 // 最后被删除的桶，没有被删除的桶时等于size
+	replacements map[int]replacement // 被删除的桶，key为桶编号
+}
+
+func newMemento() *memento {
+	return &memento{
+		replacements: make(map[int]replacement),
+	}
+}
+
+// workingCount 工作桶数
+func (m *memento) workingCount() int {
+	return m.size - len(m.replacements)
+}
+
+// add 添加一个桶，返回桶编号
+func (m *memento) add() int {
+	if len(m.replacements) <= 0 {
+		bucket := m.size
+		m.size++
+		m.lastRemoved = m.size
+		return bucket
+	}
+	bucket := m.lastRemoved
+	m.lastRemoved = m.replacements[bucket].prev
+	delete(m.replacements, bucket)
+	return bucket
+}
+
+// remove 删除一个桶
+func (m *memento) remove(bucket int) {
+	if len(m.replacements) <= 0 && bucket == m.size-1 {
+		m.size--
+		m.lastRemoved = m.size
+		return
+	}
+	m.replacements[bucket] = replacement{replacer: m.workingCount() - 1, prev: m.lastRemoved}
+	m.lastRemoved = bucket
+}
+
+// lookup 获取keyHash对应的工作桶
+func (m *memento) lookup(keyHash uint64) int {
+	bucket := int(JumpBucket(keyHash, int32(m.size)))
+	for {
+		r, ok := m.replacements[bucket]
+		if !ok {
+			return bucket
+		}
+		// 在删除b时的工作桶范围内重新哈希
+		bucket = int(utils.Mix64(keyHash^utils.Mix64(uint64(bucket))) % uint64(r.replacer))
+		// 沿着更早删除的桶的替换链查找
+		for {
+			next, ok := m.replacements[bucket]
+			if !ok || next.replacer < r.replacer {
+				break
+			}
+			bucket = next.replacer
+		}
+	}
+}
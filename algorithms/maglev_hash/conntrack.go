@@ -0,0 +1,223 @@
+package maglev_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 连接跟踪
+// 查找表变化时部分key的映射会改变，已建立的连接需要保持在原节点上。
+// ConnTrack 缓存流到节点的映射，已有的流返回缓存的节点，新的流使用当前查找表的结果。
+// 缓存按最近使用淘汰，超过容量时淘汰最久未使用的流，空闲超过idleTimeout的流失效。
+// 缓存的节点被删除时重新查询；节点被禁用时，开启健康感知查询才重新查询，否则仍返回原节点。
+// 流按flowID的哈希分到多个分片，每个分片有独立的锁和LRU，不同分片的查询互不阻塞，
+// 容量按分片平均分配，淘汰在分片内按最近使用进行。容量较小时只使用一个分片，淘汰顺序与全局LRU一致。
+// MaglevHash 由单独的读写锁保护，查询只加读锁，节点变更不阻塞流表。
+// ConnTrack 并发安全，创建后 MaglevHash 的节点变更需通过 ConnTrack 进行
+
+const (
+	connTrackShards      = 16  // 最多的分片数
+	minConnTrackShardCap = 256 // 每个分片的最小容量
+)
+
+// ConnTrackStats 连接跟踪的统计信息
+type ConnTrackStats struct {
+	Hits              uint64 // 命中缓存的查询数
+	Misses            uint64 // 未命中缓存的查询数，包括缓存的节点失效后重新查询
+	CapacityEvictions uint64 // 因超过容量被淘汰的流数量
+	IdleEvictions     uint64 // 因空闲超时被淘汰的流数量
+}
+
+// connEntry 一条流的缓存
+type connEntry struct {
+	flowID   string
+	nodeKey  string
+	lastSeen time.Time // 最后一次访问的时间
+}
+
+// connShard 流表的一个分片
+type connShard struct {
+	lock     sync.Mutex
+	capacity int                      // 分片最多跟踪的流数量
+	entries  *list.List               // 按最近访问排列的流，队首最新
+	flows    map[string]*list.Element // 流在entries中的位置，key为flowID
+	stats    ConnTrackStats
+}
+
+type ConnTrack[T models.HashNode] struct {
+	maglevLock  sync.RWMutex // 保护maglev，查询加读锁，节点变更加写锁
+	maglev      *MaglevHash[T]
+	shards      []*connShard
+	idleTimeout time.Duration    // 空闲超时，为0时不超时
+	nowFunc     func() time.Time // 当前时间，测试时可替换
+}
+
+// NewConnTrack 创建连接跟踪，capacity必须为正数，idleTimeout为0时不超时
+func NewConnTrack[T models.HashNode](maglev *MaglevHash[T], capacity int, idleTimeout time.Duration) (*ConnTrack[T], error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+	if idleTimeout < 0 {
+		return nil, fmt.Errorf("idleTimeout must not be negative, got %v", idleTimeout)
+	}
+	shardCount := min(connTrackShards, max(1, capacity/minConnTrackShardCap))
+	shards := make([]*connShard, shardCount)
+	for i := range shards {
+		// 容量的余数分给前面的分片，总容量等于capacity
+		shardCap := capacity / shardCount
+		if i < capacity%shardCount {
+			shardCap++
+		}
+		shards[i] = &connShard{
+			capacity: shardCap,
+			entries:  list.New(),
+			flows:    make(map[string]*list.Element),
+		}
+	}
+	return &ConnTrack[T]{
+		maglev:      maglev,
+		shards:      shards,
+		idleTimeout: idleTimeout,
+		nowFunc:     time.Now,
+	}, nil
+}
+
+// shard 返回流所在的分片
+func (c *ConnTrack[T]) shard(flowID string) *connShard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[utils.GetSecondaryHashCode(utils.StringToBytes(flowID))%uint64(len(c.shards))]
+}
+
+// Get 获取流对应的节点，已跟踪的流返回原节点，否则查询查找表并开始跟踪
+func (c *ConnTrack[T]) Get(flowID string) (T, error) {
+	s := c.shard(flowID)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := c.nowFunc()
+	s.expire(now, c.idleTimeout)
+	if elem, ok := s.flows[flowID]; ok {
+		entry := elem.Value.(*connEntry)
+		// 缓存的节点仍然可用时保持不变
+		if node, ok := c.getNode(entry.nodeKey); ok {
+			entry.lastSeen = now
+			s.entries.MoveToFront(elem)
+			s.stats.Hits++
+			return node, nil
+		}
+		s.remove(elem)
+	}
+	s.stats.Misses++
+	node, err := c.lookup(flowID)
+	if err != nil {
+		return node, err
+	}
+	s.flows[flowID] = s.entries.PushFront(&connEntry{flowID: flowID, nodeKey: node.GetKey(), lastSeen: now})
+	// 超过容量时淘汰最久未使用的流
+	for s.entries.Len() > s.capacity {
+		s.remove(s.entries.Back())
+		s.stats.CapacityEvictions++
+	}
+	return node, nil
+}
+
+// getNode 在读锁下查询缓存的节点是否仍然可用
+func (c *ConnTrack[T]) getNode(nodeKey string) (T, bool) {
+	c.maglevLock.RLock()
+	defer c.maglevLock.RUnlock()
+	return c.maglev.getNode(nodeKey)
+}
+
+// lookup 在读锁下查询查找表
+func (c *ConnTrack[T]) lookup(flowID string) (T, error) {
+	c.maglevLock.RLock()
+	defer c.maglevLock.RUnlock()
+	return c.maglev.Get(flowID)
+}
+
+// expire 淘汰空闲超时的流，队尾的流最久未访问
+func (s *connShard) expire(now time.Time, idleTimeout time.Duration) {
+	if idleTimeout <= 0 {
+		return
+	}
+	for elem := s.entries.Back(); elem != nil; elem = s.entries.Back() {
+		if now.Sub(elem.Value.(*connEntry).lastSeen) < idleTimeout {
+			return
+		}
+		s.remove(elem)
+		s.stats.IdleEvictions++
+	}
+}
+
+func (s *connShard) remove(elem *list.Element) {
+	s.entries.Remove(elem)
+	delete(s.flows, elem.Value.(*connEntry).flowID)
+}
+
+// Remove 停止跟踪流，如连接关闭时
+func (c *ConnTrack[T]) Remove(flowID string) {
+	s := c.shard(flowID)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if elem, ok := s.flows[flowID]; ok {
+		s.remove(elem)
+	}
+}
+
+// AddNode 添加节点，已跟踪的流不受影响
+func (c *ConnTrack[T]) AddNode(node T) {
+	c.maglevLock.Lock()
+	defer c.maglevLock.Unlock()
+	c.maglev.AddNode(node)
+}
+
+// RemoveNode 删除节点，该节点上的流在下一次查询时重新分配
+func (c *ConnTrack[T]) RemoveNode(node T) {
+	c.maglevLock.Lock()
+	defer c.maglevLock.Unlock()
+	c.maglev.RemoveNode(node)
+}
+
+// UpdateNode 更新节点实例
+func (c *ConnTrack[T]) UpdateNode(node T) {
+	c.maglevLock.Lock()
+	defer c.maglevLock.Unlock()
+	c.maglev.UpdateNode(node)
+}
+
+// SetHealthAware 开启或关闭健康感知查询，开启后禁用节点上的流在下一次查询时重新分配
+func (c *ConnTrack[T]) SetHealthAware(enabled bool) {
+	c.maglevLock.Lock()
+	defer c.maglevLock.Unlock()
+	c.maglev.SetHealthAware(enabled)
+}
+
+// Len 当前跟踪的流数量，包括已空闲超时但尚未淘汰的流
+func (c *ConnTrack[T]) Len() int {
+	count := 0
+	for _, s := range c.shards {
+		s.lock.Lock()
+		count += s.entries.Len()
+		s.lock.Unlock()
+	}
+	return count
+}
+
+// Stats 返回所有分片的统计信息之和
+func (c *ConnTrack[T]) Stats() ConnTrackStats {
+	var stats ConnTrackStats
+	for _, s := range c.shards {
+		s.lock.Lock()
+		stats.Hits += s.stats.Hits
+		stats.Misses += s.stats.Misses
+		stats.CapacityEvictions += s.stats.CapacityEvictions
+		stats.IdleEvictions += s.stats.IdleEvictions
+		s.lock.Unlock()
+	}
+	return stats
+}
@@ -0,0 +1,185 @@
+package maglev_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestConnTrack(t *testing.T, nodes []*models.NormalHashNode, capacity int,
+	idleTimeout time.Duration) *ConnTrack[*models.NormalHashNode] {
+	maglev, err := NewMaglevHash(nodes, 2039, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		t.Fatalf("new maglev hash err: %v", err)
+	}
+	conns, err := NewConnTrack(maglev, capacity, idleTimeout)
+	if err != nil {
+		t.Fatalf("new conntrack err: %v", err)
+	}
+	return conns
+}
+
+func newTestNodes(count int) []*models.NormalHashNode {
+	nodes := make([]*models.NormalHashNode, 0, count)
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	return nodes
+}
+
+func TestConnTrack_Sticky(t *testing.T) {
+	nodes := newTestNodes(20)
+	conns := newTestConnTrack(t, nodes[:10], 10000, 0)
+	flowCount := 1000
+	before := make([]string, flowCount)
+	for i := range before {
+		node, err := conns.Get("flow_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("conntrack get err: %v", err)
+		}
+		before[i] = node.GetKey()
+	}
+	// 添加节点后已有的流保持在原节点，新的流使用新的查找表
+	for _, node := range nodes[10:] {
+		conns.AddNode(node)
+	}
+	for i, nodeKey := range before {
+		if node, _ := conns.Get("flow_" + strconv.Itoa(i)); node.GetKey() != nodeKey {
+			t.Fatalf("flow_%v moved from %v to %v", i, nodeKey, node.GetKey())
+		}
+	}
+	for i := flowCount; i < 2*flowCount; i++ {
+		flowID := "flow_" + strconv.Itoa(i)
+		node, _ := conns.Get(flowID)
+		if expected, _ := conns.maglev.Get(flowID); node.GetKey() != expected.GetKey() {
+			t.Fatalf("new %v got %v, want %v", flowID, node.GetKey(), expected.GetKey())
+		}
+	}
+	stats := conns.Stats()
+	if stats.Hits != uint64(flowCount) || stats.Misses != uint64(2*flowCount) ||
+		stats.CapacityEvictions != 0 || stats.IdleEvictions != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// 未开启健康感知查询时，禁用节点上的流仍返回原节点
+	nodes[1].SetEnabled(false)
+	for i, nodeKey := range before {
+		if node, _ := conns.Get("flow_" + strconv.Itoa(i)); node.GetKey() != nodeKey {
+			t.Fatalf("flow_%v moved from %v to %v", i, nodeKey, node.GetKey())
+		}
+	}
+	if stats = conns.Stats(); stats.Hits != uint64(2*flowCount) || stats.Misses != uint64(2*flowCount) {
+		t.Fatalf("pinned flows should hit, stats %+v", stats)
+	}
+
+	// 缓存的节点被删除，或开启健康感知查询后被禁用，重新分配
+	conns.SetHealthAware(true)
+	conns.RemoveNode(nodes[0])
+	for i := range before {
+		node, err := conns.Get("flow_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("conntrack get err: %v", err)
+		}
+		if node.GetKey() == "node_0" || node.GetKey() == "node_1" {
+			t.Fatalf("flow_%v still on %v", i, node.GetKey())
+		}
+	}
+}
+
+func TestConnTrack_Eviction(t *testing.T) {
+	conns := newTestConnTrack(t, newTestNodes(10), 3, time.Minute)
+	now := time.Unix(0, 0)
+	conns.nowFunc = func() time.Time { return now }
+
+	// 超过容量时淘汰最久未使用的流
+	for _, flowID := range []string{"a", "b", "c", "a", "d"} {
+		if _, err := conns.Get(flowID); err != nil {
+			t.Fatalf("conntrack get err: %v", err)
+		}
+	}
+	if _, ok := conns.shard("b").flows["b"]; ok || conns.Len() != 3 {
+		t.Fatalf("flow b should be evicted, tracking %v flows", conns.Len())
+	}
+	stats := conns.Stats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.CapacityEvictions != 1 || stats.IdleEvictions != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// 空闲超时的流被淘汰，访问会刷新空闲时间
+	now = now.Add(50 * time.Second)
+	conns.Get("a")
+	now = now.Add(20 * time.Second)
+	conns.Get("a")
+	if _, ok := conns.shard("c").flows["c"]; ok || conns.Len() != 1 {
+		t.Fatalf("idle flows should expire, tracking %v flows", conns.Len())
+	}
+	if stats = conns.Stats(); stats.Hits != 3 || stats.CapacityEvictions != 1 || stats.IdleEvictions != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	conns.Remove("a")
+	if conns.Len() != 0 {
+		t.Fatalf("removed flow still tracked")
+	}
+
+	if _, err := NewConnTrack(conns.maglev, 0, 0); err == nil {
+		t.Fatalf("zero capacity should return error")
+	}
+}
+
+func TestConnTrack_Shards(t *testing.T) {
+	capacity := 10000
+	conns := newTestConnTrack(t, newTestNodes(10), capacity, 0)
+	if len(conns.shards) != connTrackShards {
+		t.Fatalf("got %v shards, want %v", len(conns.shards), connTrackShards)
+	}
+	flowCount := 3 * capacity
+	for i := 0; i < flowCount; i++ {
+		if _, err := conns.Get("flow_" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("conntrack get err: %v", err)
+		}
+	}
+	// 每个分片都写满，总数等于容量
+	for i, s := range conns.shards {
+		if s.entries.Len() != s.capacity {
+			t.Fatalf("shard %v tracks %v flows, capacity %v", i, s.entries.Len(), s.capacity)
+		}
+	}
+	stats := conns.Stats()
+	if conns.Len() != capacity || stats.CapacityEvictions != uint64(flowCount-capacity) {
+		t.Fatalf("unexpected stats %+v, tracking %v flows", stats, conns.Len())
+	}
+}
+
+func TestConnTrack_Concurrent(t *testing.T) {
+	nodes := newTestNodes(20)
+	// 单分片和多分片都要在节点变更时并发安全
+	for _, capacity := range []int{500, 4096} {
+		conns := newTestConnTrack(t, nodes[:10], capacity, time.Minute)
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					if _, err := conns.Get("flow_" + strconv.Itoa((w*7919+i)%(2*capacity))); err != nil {
+						t.Errorf("conntrack get err: %v", err)
+						return
+					}
+				}
+			}(w)
+		}
+		for _, node := range nodes[10:] {
+			conns.AddNode(node)
+		}
+		conns.SetHealthAware(true)
+		conns.RemoveNode(nodes[0])
+		wg.Wait()
+		stats := conns.Stats()
+		if stats.Hits+stats.Misses != 8*2000 || conns.Len() > capacity {
+			t.Fatalf("unexpected stats %+v, tracking %v flows", stats, conns.Len())
+		}
+	}
+}
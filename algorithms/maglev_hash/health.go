@@ -0,0 +1,33 @@
+package maglev_hash
+
+import "fmt"
+
+// 健康感知查询
+// 节点被禁用(IsEnabled为false)时不重建查找表，查询命中禁用节点的表项时，
+// 沿key自己的排列 (offset + i*skip) mod tableSize 依次检查其他表项，返回第一个启用节点。
+// 查找表大小为素数时排列覆盖所有表项，只要存在启用节点就能找到；
+// 同一个key的回退顺序固定，禁用节点上的key按表项比例分散到其他节点，其余key不受影响。
+// 节点恢复后key回到原节点，健康检查抖动不会触发重建
+
+// SetHealthAware 开启或关闭健康感知查询，开启后 Get 跳过禁用的节点
+func (r *MaglevHash[T]) SetHealthAware(enabled bool) {
+	r.healthAware = enabled
+}
+
+// getEnabled 从key的表项开始沿key的排列查找第一个启用节点
+func (r *MaglevHash[T]) getEnabled(key []byte, pos uint64) (T, error) {
+	var zero T
+	if idx := r.lookupTable[pos]; idx >= 0 && r.nodeList[idx].IsEnabled() {
+		return r.nodeList[idx], nil
+	}
+	// 原表项不可用时才计算key的跳跃步长
+	tableSize := uint64(r.tableSize)
+	skip := r.skipHashFunc(key)%(tableSize-1) + 1
+	for i := uint64(1); i < tableSize; i++ {
+		pos = (pos + skip) % tableSize
+		if idx := r.lookupTable[pos]; idx >= 0 && r.nodeList[idx].IsEnabled() {
+			return r.nodeList[idx], nil
+		}
+	}
+	return zero, fmt.Errorf("no enabled node")
+}
@@ -1,7 +1,7 @@
 package maglev_hash
 
 import (
-	"crypto/md5"
+	"consistent-hash/models"
 	"fmt"
 )
 
@@ -12,140 +12,219 @@ type NodePreference struct {
 	nextIdx int // 下一个排列序号
 }
 
-type MaglevHash struct {
-	nodeList       []string            // 节点列表
-	nodeMap        map[string]struct{} // 节点映射，key为nodeKey
+type MaglevHash[T models.HashNode] struct {
+	nodeList       []T                 // 节点列表
+	nodeMap        map[string]int      // 节点在nodeList中的下标，key为nodeKey
 	preferenceList []*NodePreference   // 节点偏好信息
 	tableSize      int                 // 查找表大小
-	lookupTable    []string            // 查找表
+	lookupTable    []int               // 查找表，保存节点在nodeList中的下标，-1表示未填充
+	hashFunc       func([]byte) uint64 // 哈希函数，用于计算key的位置和节点的偏移量
+	skipHashFunc   func([]byte) uint64 // 与hashFunc相互独立的哈希函数，用于计算节点的跳跃步长
+
+	autoSize          bool // 是否自动选择查找表大小
+	minimalDisruption bool // 是否开启低扰动重建
+	healthAware       bool // 是否开启健康感知查询
 }
 
-func NewMaglevHash(nodeList []string, tableSize int) *MaglevHash {
-	obj := &MaglevHash{
-		nodeList:       make([]string, 0),
-		nodeMap:        make(map[string]struct{}),
+// NewMaglevHash 创建 MaglevHash，tableSize必须为不小于2的素数，为 AutoTableSize 时按节点数自动选择
+func NewMaglevHash[T models.HashNode](nodeList []T, tableSize int,
+	hashFunc, skipHashFunc func([]byte) uint64) (*MaglevHash[T], error) {
+	autoSize := tableSize == AutoTableSize
+	if autoSize {
+		tableSize = autoTableSize(len(nodeList), autoTableSizeFactor)
+	} else if err := validateTableSize(tableSize); err != nil {
+		return nil, err
+	} else if tableSize < len(nodeList) {
+		return nil, fmt.Errorf("tableSize %d is less than node count %d", tableSize, len(nodeList))
+	}
+	obj := &MaglevHash[T]{
+		nodeList:       make([]T, 0),
+		nodeMap:        make(map[string]int),
 		preferenceList: make([]*NodePreference, 0),
 		tableSize:      tableSize,
-		lookupTable:    make([]string, tableSize),
+		lookupTable:    make([]int, tableSize),
+		hashFunc:       hashFunc,
+		skipHashFunc:   skipHashFunc,
+		autoSize:       autoSize,
 	}
-	for _, nk := range nodeList {
-		obj.AddNode(nk)
+	for idx := range obj.lookupTable {
+		obj.lookupTable[idx] = -1
 	}
-	return obj
-}
-
-func (r *MaglevHash) hash(key string, seed int) uint64 {
-	h := md5.Sum([]byte(key + string(rune(seed))))
-	// 确保结果为正数
-	result := (uint64(h[0]) << 56) | (uint64(h[1]) << 48) | (uint64(h[2]) << 40) | (uint64(h[3]) << 32) |
-		(uint64(h[4]) << 24) | (uint64(h[5]) << 16) | (uint64(h[6]) << 8) | uint64(h[7])
-	return result & 0x7fffffffffffffff // 清除符号位确保为正数
+	for _, node := range nodeList {
+		obj.AddNode(node)
+	}
+	return obj, nil
 }
 
-func (r *MaglevHash) calculatePreference(nodeKey string) *NodePreference {
-	offset := int(r.hash(nodeKey, 0)) % r.tableSize
-	skip := int(r.hash(nodeKey, 1))%(r.tableSize-1) + 1
+func (r *MaglevHash[T]) calculatePreference(nodeKey string) *NodePreference {
+	offset := r.hashFunc([]byte(nodeKey)) % uint64(r.tableSize)
+	skip := r.skipHashFunc([]byte(nodeKey))%uint64(r.tableSize-1) + 1
 	return &NodePreference{
-		offset:  offset,
-		skip:    skip,
+		offset:  int(offset),
+		skip:    int(skip),
 		nextIdx: 0,
 	}
 }
 
-func (r *MaglevHash) getPermutationItem(preference *NodePreference, index int) int {
+func (r *MaglevHash[T]) getPermutationItem(preference *NodePreference, index int) int {
 	return (preference.offset + index*preference.skip) % r.tableSize
 }
 
-func (r *MaglevHash) populateLookupTable() {
-	if len(r.nodeList) <= 0 {
-		for idx := range r.lookupTable {
-			r.lookupTable[idx] = ""
-		}
-		return
-	}
+// populateLookupTable 按轮次填充查找表，每轮中节点按权重累积额度，
+// 额度达到最大权重时按排列顺序占用一个空位，权重最大的节点每轮占用一个，
+// 各节点占用的表项数与权重成正比，权重不大于0的节点不占用表项。
+// 开启低扰动重建时先保留原有表项，节点达到配额后不再占用空位
+func (r *MaglevHash[T]) populateLookupTable() {
 	// 初始化节点偏好信息
 	r.preferenceList = make([]*NodePreference, len(r.nodeList))
-	for idx, nodeKey := range r.nodeList {
-		r.preferenceList[idx] = r.calculatePreference(nodeKey)
+	maxWeight := 0
+	for idx, node := range r.nodeList {
+		r.preferenceList[idx] = r.calculatePreference(node.GetKey())
+		maxWeight = max(maxWeight, node.GetWeight())
 	}
 	// 初始化查找表
-	for idx := range r.lookupTable {
-		r.lookupTable[idx] = ""
+	filledCount := 0
+	var quotas []int
+	if r.canKeepPreviousEntries() {
+		quotas = r.entryQuotas()
+		filledCount = r.keepPreviousEntries(quotas)
+	} else {
+		for idx := range r.lookupTable {
+			r.lookupTable[idx] = -1
+		}
 	}
-	// 重置每个节点的下一个填充位置
-	for idx := range r.preferenceList {
-		r.preferenceList[idx].nextIdx = 0
+	if maxWeight <= 0 {
+		return
 	}
+	credits := make([]int, len(r.nodeList))
 	// 按轮次填充查找表
-	filledCount := 0
 	round := 0
 	maxRounds := r.tableSize * 10 // 设置阈值
 	for filledCount < r.tableSize && round < maxRounds {
-		for i := range r.nodeList {
+		for i, node := range r.nodeList {
 			if filledCount >= r.tableSize {
 				break
 			}
-			// 找到下一个可填充的位置
-			for r.preferenceList[i].nextIdx < r.tableSize {
-				pos := r.getPermutationItem(r.preferenceList[i], r.preferenceList[i].nextIdx)
-				if r.lookupTable[pos] == "" {
-					break
-				}
-				r.preferenceList[i].nextIdx++
+			if node.GetWeight() <= 0 || (quotas != nil && quotas[i] <= 0) {
+				continue
 			}
-			// 如果还有可填充的位置，则填充
-			if r.preferenceList[i].nextIdx < r.tableSize {
-				pos := r.getPermutationItem(r.preferenceList[i], r.preferenceList[i].nextIdx)
-				r.lookupTable[pos] = r.nodeList[i]
-				r.preferenceList[i].nextIdx++
+			credits[i] += node.GetWeight()
+			if credits[i] < maxWeight {
+				continue
+			}
+			credits[i] -= maxWeight
+			if r.claimNextEntry(i) {
 				filledCount++
+				if quotas != nil {
+					quotas[i]--
+				}
 			}
 		}
 		round++
 	}
 }
 
-func (r *MaglevHash) AddNode(nodeKey string) {
+// claimNextEntry 节点按排列顺序占用下一个空位，排列已遍历完时返回false
+func (r *MaglevHash[T]) claimNextEntry(i int) bool {
+	preference := r.preferenceList[i]
+	// 找到下一个可填充的位置
+	for preference.nextIdx < r.tableSize {
+		pos := r.getPermutationItem(preference, preference.nextIdx)
+		preference.nextIdx++
+		if r.lookupTable[pos] < 0 {
+			r.lookupTable[pos] = i
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MaglevHash[T]) AddNode(node T) {
+	nodeKey := node.GetKey()
 	if _, ok := r.nodeMap[nodeKey]; ok {
 		return
 	}
-	r.nodeMap[nodeKey] = struct{}{}
-	r.nodeList = append(r.nodeList, nodeKey)
+	r.nodeMap[nodeKey] = len(r.nodeList)
+	r.nodeList = append(r.nodeList, node)
+	if r.needGrow() {
+		r.resize(autoTableSize(len(r.nodeList), 2*autoTableSizeFactor))
+		return
+	}
 	r.populateLookupTable()
 }
 
-func (r *MaglevHash) RemoveNode(nodeKey string) {
-	if _, ok := r.nodeMap[nodeKey]; !ok {
+func (r *MaglevHash[T]) RemoveNode(node T) {
+	nodeKey := node.GetKey()
+	idx, ok := r.nodeMap[nodeKey]
+	if !ok {
 		return
 	}
 	delete(r.nodeMap, nodeKey)
-
-	idx := -1
-	for i, nk := range r.nodeList {
-		if nk == nodeKey {
-			idx = i
-			break
+	r.nodeList = append(r.nodeList[:idx], r.nodeList[idx+1:]...)
+	// 删除节点之后的下标前移，查找表中的下标同步调整，被删除节点的表项置空
+	for i := idx; i < len(r.nodeList); i++ {
+		r.nodeMap[r.nodeList[i].GetKey()] = i
+	}
+	for pos, owner := range r.lookupTable {
+		if owner == idx {
+			r.lookupTable[pos] = -1
+		} else if owner > idx {
+			r.lookupTable[pos] = owner - 1
 		}
 	}
-	if idx == -1 {
+	r.populateLookupTable()
+}
+
+// UpdateNode 更新节点实例，权重变化时重新填充查找表
+func (r *MaglevHash[T]) UpdateNode(node T) {
+	idx, ok := r.nodeMap[node.GetKey()]
+	if !ok {
 		return
 	}
-	r.nodeList = append(r.nodeList[:idx], r.nodeList[idx+1:]...)
-	r.populateLookupTable()
+	oldWeight := r.nodeList[idx].GetWeight()
+	r.nodeList[idx] = node
+	if node.GetWeight() != oldWeight {
+		r.populateLookupTable()
+	}
 }
 
-func (r *MaglevHash) Get(key string) (string, error) {
-	if len(r.lookupTable) <= 0 {
-		return "", fmt.Errorf("lookupTable is empty")
+func (r *MaglevHash[T]) Get(key string) (T, error) {
+	var zero T
+	if len(r.nodeList) <= 0 || len(r.lookupTable) <= 0 {
+		return zero, fmt.Errorf("nodeList is empty")
+	}
+	keyBytes := []byte(key)
+	pos := r.hashFunc(keyBytes) % uint64(r.tableSize)
+	if r.healthAware {
+		return r.getEnabled(keyBytes, pos)
 	}
-	keyHash := int(r.hash(key, 0)) % r.tableSize
-	return r.lookupTable[keyHash], nil
+	idx := r.lookupTable[pos]
+	if idx < 0 {
+		return zero, fmt.Errorf("lookupTable entry %d is empty", pos)
+	}
+	return r.nodeList[idx], nil
 }
 
-func (r *MaglevHash) GetNodeCount() int {
+func (r *MaglevHash[T]) GetNodeCount() int {
 	return len(r.nodeList)
 }
 
-func (r *MaglevHash) GetLookupTableSize() int {
+func (r *MaglevHash[T]) GetLookupTableSize() int {
 	return len(r.lookupTable)
 }
+
+func (r *MaglevHash[T]) GetNodes() []T {
+	nodes := make([]T, len(r.nodeList))
+	copy(nodes, r.nodeList)
+	return nodes
+}
+
+// getNode 按nodeKey获取节点，开启健康感知查询时禁用的节点视为不存在
+func (r *MaglevHash[T]) getNode(nodeKey string) (T, bool) {
+	var zero T
+	idx, ok := r.nodeMap[nodeKey]
+	if !ok || (r.healthAware && !r.nodeList[idx].IsEnabled()) {
+		return zero, false
+	}
+	return r.nodeList[idx], true
+}
@@ -0,0 +1,251 @@
+package maglev_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func newTestMaglevHash(t *testing.T, nodes []models.HashNode, tableSize int) *MaglevHash[models.HashNode] {
+	obj, err := NewMaglevHash(nodes, tableSize, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		t.Fatalf("new maglev hash err: %v", err)
+	}
+	return obj
+}
+
+// countEntries 统计每个节点占用的表项数
+func countEntries[T models.HashNode](obj *MaglevHash[T]) map[string]int {
+	counts := make(map[string]int)
+	for _, idx := range obj.lookupTable {
+		if idx >= 0 {
+			counts[obj.nodeList[idx].GetKey()]++
+		}
+	}
+	return counts
+}
+
+func TestMaglevHash_WeightedEntries(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 2, true),
+		models.NewNormalHashNode("node_3", 3, true),
+		models.NewNormalHashNode("node_4", 10, true),
+		models.NewNormalHashNode("node_5", 0, true),
+	}
+	tableSize := 65537
+	obj := newTestMaglevHash(t, nodes, tableSize)
+	counts := countEntries(obj)
+	// 表项数与权重成正比，误差不超过一轮，权重为0的节点不占用表项
+	totalWeight := 16
+	for _, node := range nodes {
+		expected := float64(tableSize) * float64(node.GetWeight()) / float64(totalWeight)
+		if math.Abs(float64(counts[node.GetKey()])-expected) > 1 {
+			t.Fatalf("node %v got %v entries, want about %v", node.GetKey(), counts[node.GetKey()], expected)
+		}
+	}
+
+	// 权重全部相同时每个节点的表项数最多相差1
+	uniform := make([]models.HashNode, 0, 100)
+	for i := 0; i < 100; i++ {
+		uniform = append(uniform, models.NewNormalHashNode("node_"+strconv.Itoa(i), 5, true))
+	}
+	counts = countEntries(newTestMaglevHash(t, uniform, 2039))
+	for _, node := range uniform {
+		if count := counts[node.GetKey()]; count < 2039/100 || count > 2039/100+1 {
+			t.Fatalf("node %v got %v entries, want %v or %v", node.GetKey(), count, 2039/100, 2039/100+1)
+		}
+	}
+}
+
+func TestMaglevHash_WeightedKeys(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 2, true),
+		models.NewNormalHashNode("node_3", 5, true),
+		models.NewNormalHashNode("node_4", 8, true),
+	}
+	obj := newTestMaglevHash(t, nodes, 65537)
+	keyCount := 500000
+	counts := make(map[string]int)
+	for i := 0; i < keyCount; i++ {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("maglev hash err: %v", err)
+		}
+		counts[node.GetKey()]++
+	}
+	// 节点分到的key的比例与权重成正比
+	totalWeight := 16
+	for _, node := range nodes {
+		expected := float64(keyCount) * float64(node.GetWeight()) / float64(totalWeight)
+		if math.Abs(float64(counts[node.GetKey()])-expected) > expected*0.05 {
+			t.Fatalf("node %v got %v keys, want about %v", node.GetKey(), counts[node.GetKey()], expected)
+		}
+	}
+}
+
+func TestMaglevHash_UpdateWeight(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 1, true),
+	}
+	obj := newTestMaglevHash(t, nodes, 2039)
+	// 修改权重后重新填充查找表
+	obj.UpdateNode(models.NewNormalHashNode("node_2", 3, true))
+	counts := countEntries(obj)
+	if math.Abs(float64(counts["node_2"])-2039*0.75) > 1 {
+		t.Fatalf("node_2 got %v entries after update, want about %v", counts["node_2"], 2039*0.75)
+	}
+	// 所有节点权重为0时查询返回错误
+	obj.UpdateNode(models.NewNormalHashNode("node_1", 0, true))
+	obj.UpdateNode(models.NewNormalHashNode("node_2", 0, true))
+	if _, err := obj.Get("key"); err == nil {
+		t.Fatalf("zero weight nodes should return error")
+	}
+}
+
+func TestMaglevHash_MinimalDisruption(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 110)
+	for i := 0; i < 110; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	tableSize := 65537
+	for _, minimalDisruption := range []bool{false, true} {
+		obj := newTestMaglevHash(t, nodes[:100], tableSize)
+		obj.SetMinimalDisruption(minimalDisruption)
+		before := make([]string, tableSize)
+		for pos, idx := range obj.lookupTable {
+			before[pos] = obj.nodeList[idx].GetKey()
+		}
+		for _, node := range nodes[100:] {
+			obj.AddNode(node)
+		}
+		obj.RemoveNode(nodes[0])
+		changed := 0
+		for pos, idx := range obj.lookupTable {
+			if obj.nodeList[idx].GetKey() != before[pos] {
+				changed++
+			}
+		}
+		// 低扰动重建只改变新节点获得的表项和被删除节点的表项
+		limit := tableSize*10/109 + tableSize/100 + 1
+		t.Logf("minimalDisruption %v: %v entries changed, limit %v", minimalDisruption, changed, limit)
+		if minimalDisruption && changed > limit {
+			t.Fatalf("%v entries changed, want at most %v", changed, limit)
+		}
+		// 仍然满足负载均衡，各节点的表项数最多相差1
+		counts := countEntries(obj)
+		for _, node := range obj.GetNodes() {
+			if count := counts[node.GetKey()]; count < tableSize/109 || count > tableSize/109+1 {
+				t.Fatalf("node %v got %v entries, want %v or %v", node.GetKey(), count, tableSize/109, tableSize/109+1)
+			}
+		}
+	}
+}
+
+func TestMaglevHash_TableSize(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 50)
+	for i := 0; i < 50; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	for _, tableSize := range []int{-1, 1, 4, 2040, 7} {
+		if _, err := NewMaglevHash(nodes[:10], tableSize, utils.GetHashCode, utils.GetSecondaryHashCode); err == nil {
+			t.Fatalf("tableSize %d should return error", tableSize)
+		}
+	}
+	// 自动模式取不小于节点数100倍的最小素数
+	obj := newTestMaglevHash(t, nodes[:10], AutoTableSize)
+	if obj.GetLookupTableSize() != utils.NextPrime(1000) || !obj.IsAutoSize() {
+		t.Fatalf("auto tableSize %v, want %v", obj.GetLookupTableSize(), utils.NextPrime(1000))
+	}
+	// 节点增加后自动扩容，预留一倍空间
+	for _, node := range nodes[10:] {
+		obj.AddNode(node)
+		tableSize, nodeCount := obj.GetLookupTableSize(), obj.GetNodeCount()
+		if tableSize < nodeCount*100 || tableSize > utils.NextPrime(nodeCount*200) || !utils.IsPrime(tableSize) {
+			t.Fatalf("tableSize %v for %v nodes", tableSize, nodeCount)
+		}
+	}
+	// 手动修改大小后关闭自动模式
+	if err := obj.Resize(1000); err == nil {
+		t.Fatalf("resize to composite should return error")
+	}
+	if err := obj.Resize(65537); err != nil || obj.GetLookupTableSize() != 65537 || obj.IsAutoSize() {
+		t.Fatalf("resize err: %v, tableSize %v", err, obj.GetLookupTableSize())
+	}
+	counts := countEntries(obj)
+	for _, node := range nodes {
+		if count := counts[node.GetKey()]; count < 65537/50 || count > 65537/50+1 {
+			t.Fatalf("node %v got %v entries after resize", node.GetKey(), count)
+		}
+	}
+}
+
+func TestMaglevHash_HealthAware(t *testing.T) {
+	nodes := make([]*models.NormalHashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj, err := NewMaglevHash(nodes, 2039, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		t.Fatalf("new maglev hash err: %v", err)
+	}
+	obj.SetHealthAware(true)
+	keyCount := 20000
+	before := make([]string, keyCount)
+	for i := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("maglev hash err: %v", err)
+		}
+		before[i] = node.GetKey()
+	}
+	table := make([]int, len(obj.lookupTable))
+	copy(table, obj.lookupTable)
+
+	// 禁用节点后只有该节点上的key迁移，并分散到其他节点，查找表不变
+	nodes[3].SetEnabled(false)
+	moved := make(map[string]int)
+	for i, nodeKey := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("maglev hash err: %v", err)
+		}
+		if !node.IsEnabled() {
+			t.Fatalf("key_%v got disabled node %v", i, node.GetKey())
+		}
+		if nodeKey != "node_3" && node.GetKey() != nodeKey {
+			t.Fatalf("key_%v moved from %v to %v", i, nodeKey, node.GetKey())
+		}
+		if nodeKey == "node_3" {
+			moved[node.GetKey()]++
+		}
+	}
+	if len(moved) != 9 {
+		t.Fatalf("keys of disabled node moved to %v nodes, want 9", len(moved))
+	}
+	for pos, idx := range table {
+		if obj.lookupTable[pos] != idx {
+			t.Fatalf("lookupTable changed at %v", pos)
+		}
+	}
+
+	// 恢复后回到原节点
+	nodes[3].SetEnabled(true)
+	for i, nodeKey := range before {
+		if node, _ := obj.Get("key_" + strconv.Itoa(i)); node.GetKey() != nodeKey {
+			t.Fatalf("key_%v got %v after recovery, want %v", i, node.GetKey(), nodeKey)
+		}
+	}
+
+	// 所有节点都被禁用时返回错误
+	for _, node := range nodes {
+		node.SetEnabled(false)
+	}
+	if _, err = obj.Get("key"); err == nil {
+		t.Fatalf("all disabled nodes should return error")
+	}
+}
@@ -0,0 +1,101 @@
+package maglev_hash
+
+import (
+	"consistent-hash/utils"
+	"sort"
+)
+
+// 低扰动重建
+// 节点变化时以变化前的查找表为参考：
+// 1. 按权重计算每个节点应占用的表项数(配额)，各节点配额相差不超过按权重分配的余数，保证负载均衡
+// 2. 保留仍然存在的节点原有的表项，超出配额时优先保留在该节点排列中靠前的表项
+// 3. 剩余的空位按轮次由未达到配额的节点按排列顺序填充
+// 添加或删除少量节点时，只有新节点获得的表项和被删除节点的表项发生变化。
+// 表项位置在排列中的序号通过跳跃步长的模逆元计算，因此要求查找表大小为素数，否则退化为完整重建
+
+// SetMinimalDisruption 开启或关闭低扰动重建，下一次节点变化时生效
+func (r *MaglevHash[T]) SetMinimalDisruption(enabled bool) {
+	r.minimalDisruption = enabled
+}
+
+// keepPreviousEntries 清空查找表，保留每个节点不超过配额的原有表项，返回保留的表项数，quotas更新为剩余配额
+func (r *MaglevHash[T]) keepPreviousEntries(quotas []int) int {
+	entries := make([][]int, len(r.nodeList))
+	for pos, owner := range r.lookupTable {
+		if owner >= 0 {
+			entries[owner] = append(entries[owner], pos)
+		}
+		r.lookupTable[pos] = -1
+	}
+	kept := 0
+	for i, positions := range entries {
+		if len(positions) > quotas[i] {
+			// 优先保留在排列中靠前的表项
+			preference := r.preferenceList[i]
+			inverse := modInverse(preference.skip, r.tableSize)
+			rank := func(pos int) int {
+				return (pos - preference.offset + r.tableSize) % r.tableSize * inverse % r.tableSize
+			}
+			sort.Slice(positions, func(a, b int) bool {
+				return rank(positions[a]) < rank(positions[b])
+			})
+			positions = positions[:quotas[i]]
+		}
+		for _, pos := range positions {
+			r.lookupTable[pos] = i
+		}
+		quotas[i] -= len(positions)
+		kept += len(positions)
+	}
+	return kept
+}
+
+// entryQuotas 按权重计算每个节点的配额，余数按最大余数法分配，相同余数时下标小的节点优先
+func (r *MaglevHash[T]) entryQuotas() []int {
+	quotas := make([]int, len(r.nodeList))
+	totalWeight := 0
+	for _, node := range r.nodeList {
+		totalWeight += max(node.GetWeight(), 0)
+	}
+	if totalWeight <= 0 {
+		return quotas
+	}
+	remainders := make([]int, len(r.nodeList))
+	order := make([]int, 0, len(r.nodeList))
+	assigned := 0
+	for i, node := range r.nodeList {
+		weight := max(node.GetWeight(), 0)
+		quotas[i] = r.tableSize * weight / totalWeight
+		remainders[i] = r.tableSize * weight % totalWeight
+		assigned += quotas[i]
+		if weight > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for j := 0; assigned < r.tableSize; j++ {
+		quotas[order[j]]++
+		assigned++
+	}
+	return quotas
+}
+
+// canKeepPreviousEntries 是否可以参考变化前的查找表重建
+func (r *MaglevHash[T]) canKeepPreviousEntries() bool {
+	return r.minimalDisruption && utils.IsPrime(r.tableSize)
+}
+
+// modInverse a在模m下的逆元，要求a与m互素
+func modInverse(a, m int) int {
+	// 扩展欧几里得算法
+	oldR, curR := a, m
+	oldS, curS := 1, 0
+	for curR != 0 {
+		q := oldR / curR
+		oldR, curR = curR, oldR-q*curR
+		oldS, curS = curS, oldS-q*curS
+	}
+	return (oldS%m + m) % m
+}
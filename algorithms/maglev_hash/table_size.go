@@ -0,0 +1,66 @@
+package maglev_hash
+
+import (
+	"consistent-hash/utils"
+	"fmt"
+)
+
+// 查找表大小
+// 查找表大小必须为素数，保证每个节点的排列覆盖所有表项；表项数远大于节点数时负载更均衡，论文建议取节点数的100倍以上。
+// 大小为0时自动选择：取不小于节点数100倍的最小素数，节点增加到表项数不足节点数的100倍时，
+// 扩容为不小于节点数200倍的最小素数，预留空间避免频繁扩容。扩容会改变大部分key的映射
+
+const (
+	AutoTableSize       = 0   // 自动选择查找表大小
+	autoTableSizeFactor = 100 // 自动模式下查找表大小至少为节点数的倍数
+)
+
+// validateTableSize 校验查找表大小
+func validateTableSize(tableSize int) error {
+	if tableSize < 2 {
+		return fmt.Errorf("tableSize must be at least 2, got %d", tableSize)
+	}
+	if !utils.IsPrime(tableSize) {
+		return fmt.Errorf("tableSize must be a prime, got %d", tableSize)
+	}
+	return nil
+}
+
+// autoTableSize 不小于nodeCount*factor的最小素数
+func autoTableSize(nodeCount, factor int) int {
+	return utils.NextPrime(max(nodeCount*factor, 2))
+}
+
+// Resize 修改查找表大小并重新填充，tableSize为 AutoTableSize 时切换为自动模式。
+// 查找表大小变化后大部分key的映射都会改变，低扰动重建也无法保留原有表项
+func (r *MaglevHash[T]) Resize(tableSize int) error {
+	autoSize := tableSize == AutoTableSize
+	if autoSize {
+		tableSize = autoTableSize(len(r.nodeList), autoTableSizeFactor)
+	} else if err := validateTableSize(tableSize); err != nil {
+		return err
+	}
+	r.autoSize = autoSize
+	r.resize(tableSize)
+	return nil
+}
+
+// resize 重新分配查找表并填充
+func (r *MaglevHash[T]) resize(tableSize int) {
+	r.tableSize = tableSize
+	r.lookupTable = make([]int, tableSize)
+	for idx := range r.lookupTable {
+		r.lookupTable[idx] = -1
+	}
+	r.populateLookupTable()
+}
+
+// needGrow 自动模式下表项数不足节点数的100倍时需要扩容
+func (r *MaglevHash[T]) needGrow() bool {
+	return r.autoSize && r.tableSize < len(r.nodeList)*autoTableSizeFactor
+}
+
+// IsAutoSize 是否自动选择查找表大小
+func (r *MaglevHash[T]) IsAutoSize() bool {
+	return r.autoSize
+}
@@ -0,0 +1,168 @@
+package algorithms
+
+import (
+	"consistent-hash/algorithms/maglev_hash"
+	"consistent-hash/utils"
+	"fmt"
+)
+
+const (
+	defaultVnodeBaseNum    = 160
+	defaultRingFloorLimit  = 1
+	defaultMaglevTableSize = 65537
+)
+
+// Options 算法参数，每个算法声明自己的参数类型，由工厂在创建实例前校验
+type Options interface {
+	// Validate 校验参数，nodeCount为初始节点数量
+	Validate(nodeCount int) error
+}
+
+// RingHashOptions RingHash 参数
+type RingHashOptions struct {
+	VnodeBaseNum   int                 `json:"vnode_base_num"`   // 虚拟节点基数
+	RingFloorLimit int                 `json:"ring_floor_limit"` // 建环的下限
+	HashFunc       func([]byte) uint64 `json:"-"`                // 哈希函数，为空时使用默认哈希函数
+}
+
+func DefaultRingHashOptions() *RingHashOptions {
+	return &RingHashOptions{
+		VnodeBaseNum:   defaultVnodeBaseNum,
+		RingFloorLimit: defaultRingFloorLimit,
+	}
+}
+
+func (o *RingHashOptions) Validate(nodeCount int) error {
+	if o.VnodeBaseNum <= 0 {
+		return fmt.Errorf("vnodeBaseNum must be positive, got %d", o.VnodeBaseNum)
+	}
+	if o.RingFloorLimit < 0 {
+		return fmt.Errorf("ringFloorLimit must not be negative, got %d", o.RingFloorLimit)
+	}
+	return nil
+}
+
+// HashOptions 只需要哈希函数的算法参数，适用于 JumpHash、加权 JumpHash、RendezvousHash、SlotHash
+type HashOptions struct {
+	HashFunc func([]byte) uint64 `json:"-"` // 哈希函数，为空时使用默认哈希函数
+}
+
+func DefaultHashOptions() *HashOptions {
+	return &HashOptions{}
+}
+
+func (o *HashOptions) Validate(nodeCount int) error {
+	return nil
+}
+
+// HierarchicalRendezvousHashOptions 层次化 RendezvousHash 参数
+type HierarchicalRendezvousHashOptions struct {
+	Fanout   int                 `json:"fanout"` // 虚拟树每个内部节点的子节点数，为0时使用默认值8
+	HashFunc func([]byte) uint64 `json:"-"`      // 哈希函数，为空时使用默认哈希函数
+}
+
+func DefaultHierarchicalRendezvousHashOptions() *HierarchicalRendezvousHashOptions {
+	return &HierarchicalRendezvousHashOptions{}
+}
+
+func (o *HierarchicalRendezvousHashOptions) Validate(nodeCount int) error {
+	if o.Fanout < 0 || o.Fanout == 1 {
+		return fmt.Errorf("fanout must be 0 or at least 2, got %d", o.Fanout)
+	}
+	return nil
+}
+
+// MaglevHashOptions MaglevHash 参数
+type MaglevHashOptions struct {
+	TableSize         int                 `json:"table_size"`         // 查找表大小，必须为素数且不小于节点数，为0时按节点数自动选择并随节点增加扩容
+	HashFunc          func([]byte) uint64 `json:"-"`                  // 哈希函数，为空时使用默认哈希函数
+	SkipHashFunc      func([]byte) uint64 `json:"-"`                  // 计算跳跃步长的哈希函数，需与HashFunc相互独立，为空时使用默认的第二哈希函数
+	MinimalDisruption bool                `json:"minimal_disruption"` // 是否开启低扰动重建，节点变化时尽量保留原有表项
+	HealthAware       bool                `json:"health_aware"`       // 是否开启健康感知查询，跳过禁用的节点而不重建查找表
+}
+
+func DefaultMaglevHashOptions() *MaglevHashOptions {
+	return &MaglevHashOptions{
+		TableSize: defaultMaglevTableSize,
+	}
+}
+
+func (o *MaglevHashOptions) Validate(nodeCount int) error {
+	if o.TableSize == maglev_hash.AutoTableSize {
+		return nil
+	}
+	if !utils.IsPrime(o.TableSize) {
+		return fmt.Errorf("tableSize must be a prime, got %d", o.TableSize)
+	}
+	if o.TableSize < nodeCount {
+		return fmt.Errorf("tableSize %d is less than node count %d", o.TableSize, nodeCount)
+	}
+	return nil
+}
+
+// AnchorHashOptions AnchorHash 参数
+type AnchorHashOptions struct {
+	Capacity int                 `json:"capacity"` // 最大桶容量，为0时取节点数的2倍
+	HashFunc func([]byte) uint64 `json:"-"`        // 哈希函数，为空时使用默认哈希函数
+}
+
+func DefaultAnchorHashOptions() *AnchorHashOptions {
+	return &AnchorHashOptions{}
+}
+
+func (o *AnchorHashOptions) Validate(nodeCount int) error {
+	if o.Capacity < 0 {
+		return fmt.Errorf("capacity must not be negative, got %d", o.Capacity)
+	}
+	if o.Capacity > 0 && o.Capacity < nodeCount {
+		return fmt.Errorf("capacity %d is less than node count %d", o.Capacity, nodeCount)
+	}
+	return nil
+}
+
+func (o *AnchorHashOptions) capacity(nodeCount int) int {
+	if o.Capacity > 0 {
+		return o.Capacity
+	}
+	return max(2*nodeCount, 1)
+}
+
+// DxHashOptions DxHash 参数
+type DxHashOptions struct {
+	InitSize int                 `json:"init_size"` // NSArray初始大小，为0时取节点数
+	HashFunc func([]byte) uint64 `json:"-"`         // 哈希函数，为空时使用MD5，与未指定时的历史结果保持一致
+}
+
+func DefaultDxHashOptions() *DxHashOptions {
+	return &DxHashOptions{}
+}
+
+func (o *DxHashOptions) Validate(nodeCount int) error {
+	if o.InitSize < 0 {
+		return fmt.Errorf("initSize must not be negative, got %d", o.InitSize)
+	}
+	return nil
+}
+
+func (o *DxHashOptions) initSize(nodeCount int) int {
+	if o.InitSize > 0 {
+		return o.InitSize
+	}
+	return max(nodeCount, 1)
+}
+
+// hashFuncOrDefault 未指定哈希函数时使用默认哈希函数
+func hashFuncOrDefault(hashFunc func([]byte) uint64) func([]byte) uint64 {
+	if hashFunc == nil {
+		return utils.GetHashCode
+	}
+	return hashFunc
+}
+
+// secondaryHashFuncOrDefault 未指定第二哈希函数时使用默认的第二哈希函数
+func secondaryHashFuncOrDefault(hashFunc func([]byte) uint64) func([]byte) uint64 {
+	if hashFunc == nil {
+		return utils.GetSecondaryHashCode
+	}
+	return hashFunc
+}
@@ -0,0 +1,200 @@
+package algorithms
+
+import (
+	"consistent-hash/models"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Factory 算法工厂，NewOptions 返回带默认值的参数，Build 使用校验后的参数创建实例
+type Factory struct {
+	NewOptions func() Options
+	Build      func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error)
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]Factory)
+)
+
+func init() {
+	mustRegister("ring", Factory{
+		NewOptions: func() Options { return DefaultRingHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*RingHashOptions)
+			if !ok {
+				return nil, optionsTypeError("ring", opts)
+			}
+			return NewRingHashBalancer(o.VnodeBaseNum, o.RingFloorLimit, nodes, hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+	mustRegister("jump", Factory{
+		NewOptions: func() Options { return DefaultHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*HashOptions)
+			if !ok {
+				return nil, optionsTypeError("jump", opts)
+			}
+			return NewJumpHashBalancer(nodes, hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+	mustRegister("weighted_jump", Factory{
+		NewOptions: func() Options { return DefaultHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*HashOptions)
+			if !ok {
+				return nil, optionsTypeError("weighted_jump", opts)
+			}
+			return NewWeightedJumpHashBalancer(nodes, hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+	mustRegister("rendezvous", Factory{
+		NewOptions: func() Options { return DefaultHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*HashOptions)
+			if !ok {
+				return nil, optionsTypeError("rendezvous", opts)
+			}
+			return NewRendezvousHashBalancer(nodes, hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+	mustRegister("hierarchical_rendezvous", Factory{
+		NewOptions: func() Options { return DefaultHierarchicalRendezvousHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*HierarchicalRendezvousHashOptions)
+			if !ok {
+				return nil, optionsTypeError("hierarchical_rendezvous", opts)
+			}
+			return NewHierarchicalRendezvousHashBalancer(nodes, o.Fanout, hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+	mustRegister("maglev", Factory{
+		NewOptions: func() Options { return DefaultMaglevHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*MaglevHashOptions)
+			if !ok {
+				return nil, optionsTypeError("maglev", opts)
+			}
+			return NewMaglevHashBalancer(nodes, o)
+		},
+	})
+	mustRegister("anchor", Factory{
+		NewOptions: func() Options { return DefaultAnchorHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*AnchorHashOptions)
+			if !ok {
+				return nil, optionsTypeError("anchor", opts)
+			}
+			return NewAnchorHashBalancer(nodes, o.capacity(len(nodes)), hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+	mustRegister("dx", Factory{
+		NewOptions: func() Options { return DefaultDxHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*DxHashOptions)
+			if !ok {
+				return nil, optionsTypeError("dx", opts)
+			}
+			return NewDxHashBalancer(nodes, o.initSize(len(nodes)), o.HashFunc), nil
+		},
+	})
+	mustRegister("slot", Factory{
+		NewOptions: func() Options { return DefaultHashOptions() },
+		Build: func(nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+			o, ok := opts.(*HashOptions)
+			if !ok {
+				return nil, optionsTypeError("slot", opts)
+			}
+			return NewSlotHashBalancer(nodes, hashFuncOrDefault(o.HashFunc)), nil
+		},
+	})
+}
+
+func optionsTypeError(name string, opts Options) error {
+	return fmt.Errorf("algorithm %s got unexpected options type %T", name, opts)
+}
+
+func mustRegister(name string, factory Factory) {
+	if err := Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Register 注册算法，名称重复时返回错误
+func Register(name string, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("algorithm name is empty")
+	}
+	if factory.NewOptions == nil || factory.Build == nil {
+		return fmt.Errorf("algorithm %s factory is incomplete", name)
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("algorithm %s already registered", name)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// Names 返回所有已注册的算法名称
+func Names() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getFactory(name string) (Factory, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, ok := registry[name]
+	if !ok {
+		return Factory{}, fmt.Errorf("unknown algorithm: %s", name)
+	}
+	return factory, nil
+}
+
+// NewOptions 返回算法带默认值的参数，可用于从配置文件中解析参数
+func NewOptions(name string) (Options, error) {
+	factory, err := getFactory(name)
+	if err != nil {
+		return nil, err
+	}
+	return factory.NewOptions(), nil
+}
+
+// New 按名称创建算法实例，opts 为空时使用默认参数，包括类型正确的空指针如 (*RingHashOptions)(nil)
+func New(name string, nodes []models.HashNode, opts Options) (Balancer[models.HashNode], error) {
+	factory, err := getFactory(name)
+	if err != nil {
+		return nil, err
+	}
+	if isNilOptions(opts) {
+		defaults := factory.NewOptions()
+		// 类型不匹配的空指针同样视为参数类型错误
+		if opts != nil && reflect.TypeOf(opts) != reflect.TypeOf(defaults) {
+			return nil, optionsTypeError(name, opts)
+		}
+		opts = defaults
+	}
+	if err = opts.Validate(len(nodes)); err != nil {
+		return nil, fmt.Errorf("algorithm %s invalid options: %w", name, err)
+	}
+	return factory.Build(nodes, opts)
+}
+
+// isNilOptions opts 为nil接口或包装了空指针时返回true，空指针调用Validate会panic
+func isNilOptions(opts Options) bool {
+	if opts == nil {
+		return true
+	}
+	v := reflect.ValueOf(opts)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,133 @@
+package algorithms
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestRegistry_New(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 100, true))
+	}
+	for _, name := range Names() {
+		balancer, err := New(name, nodes, nil)
+		if err != nil {
+			t.Fatalf("%s: new err: %v", name, err)
+		}
+		if balancer.Len() != len(nodes) {
+			t.Fatalf("%s: node count %v, want %v", name, balancer.Len(), len(nodes))
+		}
+	}
+	if _, err := New("unknown", nodes, nil); err == nil {
+		t.Fatalf("unknown algorithm should return error")
+	}
+	if _, err := New("maglev", nodes, DefaultRingHashOptions()); err == nil {
+		t.Fatalf("mismatched options type should return error")
+	}
+
+	// 类型正确的空指针使用默认参数，类型不匹配的空指针返回错误
+	typedNil := map[string]Options{
+		"ring":                    (*RingHashOptions)(nil),
+		"jump":                    (*HashOptions)(nil),
+		"hierarchical_rendezvous": (*HierarchicalRendezvousHashOptions)(nil),
+		"maglev":                  (*MaglevHashOptions)(nil),
+		"anchor":                  (*AnchorHashOptions)(nil),
+		"dx":                      (*DxHashOptions)(nil),
+	}
+	for name, opts := range typedNil {
+		balancer, err := New(name, nodes, opts)
+		if err != nil {
+			t.Fatalf("%s: typed nil options err: %v", name, err)
+		}
+		if balancer.Len() != len(nodes) {
+			t.Fatalf("%s: node count %v, want %v", name, balancer.Len(), len(nodes))
+		}
+	}
+	if _, err := New("maglev", nodes, (*RingHashOptions)(nil)); err == nil {
+		t.Fatalf("mismatched typed nil options should return error")
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 1, true))
+	}
+	invalid := map[string]Options{
+		"ring":                    &RingHashOptions{VnodeBaseNum: 0, RingFloorLimit: 1},
+		"maglev":                  &MaglevHashOptions{TableSize: 2040},
+		"anchor":                  &AnchorHashOptions{Capacity: 5},
+		"dx":                      &DxHashOptions{InitSize: -1},
+		"hierarchical_rendezvous": &HierarchicalRendezvousHashOptions{Fanout: 1},
+	}
+	for name, opts := range invalid {
+		if _, err := New(name, nodes, opts); err == nil {
+			t.Fatalf("%s: invalid options %+v should return error", name, opts)
+		}
+	}
+	for _, tableSize := range []int{-1, 1, 7} {
+		if _, err := New("maglev", nodes, &MaglevHashOptions{TableSize: tableSize}); err == nil {
+			t.Fatalf("maglev: tableSize %d should return error", tableSize)
+		}
+	}
+	// tableSize为0时自动选择
+	if _, err := New("maglev", nodes, &MaglevHashOptions{TableSize: 0}); err != nil {
+		t.Fatalf("maglev: auto tableSize err: %v", err)
+	}
+
+	// 从配置中解析参数
+	opts, err := NewOptions("maglev")
+	if err != nil {
+		t.Fatalf("new options err: %v", err)
+	}
+	if err = json.Unmarshal([]byte(`{"table_size": 2039, "health_aware": true}`), opts); err != nil {
+		t.Fatalf("unmarshal options err: %v", err)
+	}
+	balancer, err := New("maglev", nodes, opts)
+	if err != nil {
+		t.Fatalf("maglev: new err: %v", err)
+	}
+	// 注册表与适配器使用同一个构造函数，参数同样生效
+	if size := balancer.(*maglevHashBalancer[models.HashNode]).maglev.GetLookupTableSize(); size != 2039 {
+		t.Fatalf("maglev: tableSize %v, want 2039", size)
+	}
+	nodes[0].SetEnabled(false)
+	defer nodes[0].SetEnabled(true)
+	for i := 0; i < 1000; i++ {
+		node, err := balancer.Get(fmt.Sprintf("key_%d", i))
+		if err != nil {
+			t.Fatalf("maglev: get err: %v", err)
+		}
+		if !node.IsEnabled() {
+			t.Fatalf("maglev: health aware get disabled node %v", node.GetKey())
+		}
+	}
+}
+
+func TestRegistry_DxHashFunc(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 1, true))
+	}
+	// 指定的哈希函数同时用于节点和key
+	calls := 0
+	hashFunc := func(data []byte) uint64 {
+		calls++
+		return utils.GetHashCode(data)
+	}
+	balancer, err := New("dx", nodes, &DxHashOptions{HashFunc: hashFunc})
+	if err != nil {
+		t.Fatalf("dx: new err: %v", err)
+	}
+	if calls < len(nodes) {
+		t.Fatalf("dx: hashFunc called %v times for %v nodes", calls, len(nodes))
+	}
+	calls = 0
+	if _, err = balancer.Get("key"); err != nil || calls == 0 {
+		t.Fatalf("dx: get err: %v, hashFunc called %v times", err, calls)
+	}
+}
@@ -0,0 +1,106 @@
+package rendezvous_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"strconv"
+	"testing"
+)
+
+// legacyComputeWeight 旧的打分方式: 每个节点拼接 key + "-" + nodeKey 后哈希，仅用于对比
+func legacyComputeWeight(key string, node models.HashNode) float64 {
+	hashCode := utils.GetHashCode([]byte(key + "-" + node.GetKey()))
+	return utils.WeightedHashScore(hashCode, node.GetWeight())
+}
+
+func legacyGet(nodes []models.HashNode, key string) models.HashNode {
+	selectNode := nodes[0]
+	maxWeight := legacyComputeWeight(key, selectNode)
+	for _, node := range nodes[1:] {
+		if weight := legacyComputeWeight(key, node); weight > maxWeight {
+			selectNode, maxWeight = node, weight
+		}
+	}
+	return selectNode
+}
+
+func benchmarkNodes(count int, weighted bool) []models.HashNode {
+	nodes := make([]models.HashNode, 0, count)
+	for i := 0; i < count; i++ {
+		weight := 1
+		if weighted {
+			weight = 1 + i%4
+		}
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), weight, true))
+	}
+	return nodes
+}
+
+func benchmarkKeys() []string {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key_" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func TestRendezvousHash_NoAlloc(t *testing.T) {
+	for _, weighted := range []bool{false, true} {
+		obj := NewRendezvousHash(benchmarkNodes(100, weighted), utils.GetHashCode)
+		allocs := testing.AllocsPerRun(100, func() {
+			obj.Get("photoId_1")
+		})
+		if allocs != 0 {
+			t.Fatalf("weighted %v: Get allocs %v, want 0", weighted, allocs)
+		}
+	}
+}
+
+// 新旧实现使用相同的节点成对比较: _Get 权重相同，_GetWeighted 权重为1到4
+
+func benchmarkGet(b *testing.B, weighted bool) {
+	obj := NewRendezvousHash(benchmarkNodes(1000, weighted), utils.GetHashCode)
+	keys := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		obj.Get(keys[i&(len(keys)-1)])
+	}
+}
+
+func benchmarkLegacyGet(b *testing.B, weighted bool) {
+	nodes := benchmarkNodes(1000, weighted)
+	keys := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		legacyGet(nodes, keys[i&(len(keys)-1)])
+	}
+}
+
+func BenchmarkRendezvousHash_Get(b *testing.B) {
+	benchmarkGet(b, false)
+}
+
+func BenchmarkLegacyRendezvousHash_Get(b *testing.B) {
+	benchmarkLegacyGet(b, false)
+}
+
+func BenchmarkRendezvousHash_GetWeighted(b *testing.B) {
+	benchmarkGet(b, true)
+}
+
+func BenchmarkLegacyRendezvousHash_GetWeighted(b *testing.B) {
+	benchmarkLegacyGet(b, true)
+}
+
+// BenchmarkHierarchicalRendezvousHash_Get 与 BenchmarkRendezvousHash_GetWeighted 使用相同的节点对比
+func BenchmarkHierarchicalRendezvousHash_Get(b *testing.B) {
+	obj := NewHierarchicalRendezvousHash(benchmarkNodes(1000, true), defaultSkeletonFanout, utils.GetHashCode)
+	keys := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		obj.Get(keys[i&(len(keys)-1)])
+	}
+}
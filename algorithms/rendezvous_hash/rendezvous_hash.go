@@ -2,13 +2,21 @@ package rendezvous_hash
 
 import (
 	"consistent-hash/models"
+	"consistent-hash/utils"
 	"fmt"
+	"sort"
 )
 
+const maxWeightClasses = 8 // 按权重分组比较时允许的不同权重数
+
 type RendezvousHash[T models.HashNode] struct {
-	nodeList []T                 // 节点列表
-	nodeMap  map[string]struct{} // 节点映射，key为nodeKey
-	hashFunc func([]byte) uint64 // 哈希函数
+	nodeList      []T                 // 节点列表
+	nodeSeeds     []uint64            // 节点的种子，与 nodeList 一一对应
+	nodeWeights   []int               // 节点的权重，与 nodeList 一一对应
+	nodeClasses   []uint8             // 节点所在权重分组的下标，与 nodeList 一一对应
+	weightClasses []int               // 节点的不同权重，超过 maxWeightClasses 个时为空
+	nodeMap       map[string]struct{} // 节点映射，key为nodeKey
+	hashFunc      func([]byte) uint64 // 哈希函数
 }
 
 func NewRendezvousHash[T models.HashNode](nodeList []T, hashFunc func([]byte) uint64) *RendezvousHash[T] {
@@ -23,10 +31,54 @@ func NewRendezvousHash[T models.HashNode](nodeList []T, hashFunc func([]byte) ui
 	return obj
 }
 
-func (r *RendezvousHash[T]) computeWeight(key string, node T) uint64 {
-	merged := key + "-" + node.GetKey()
-	hashCode := r.hashFunc([]byte(merged))
-	return hashCode * uint64(node.GetWeight())
+// 查询时key只哈希一次，与每个节点预先计算的种子混淆后得到该节点的哈希，不产生内存分配。
+// 节点的排序依据为 (得分, 哈希的高53位, nodeKey)：得分高的优先，得分相同时哈希大的优先，再相同时nodeKey小的优先。
+// 权重相同的节点得分随哈希单调不减，按 (哈希, nodeKey) 比较与完整比较的结果一致，
+// 因此 Get 先在每个权重分组内比较哈希，只需为每组的最优节点计算一次对数；所有节点权重相同时无需计算对数。
+// Get、Rank、GetN 使用同一个比较函数 higherScore，Get 的结果总是 Rank 的第一个节点
+
+// nodeHash 节点i对keyHash的哈希，keyHash与节点种子异或后用 Mix64 打散
+func (r *RendezvousHash[T]) nodeHash(keyHash uint64, i int) uint64 {
+	return utils.Mix64(keyHash ^ r.nodeSeeds[i])
+}
+
+// scoreHash 计算得分使用的哈希高53位，得分相同时参与比较；权重不大于0时得分都为0，记为0只按nodeKey比较
+func (r *RendezvousHash[T]) scoreHash(keyHash uint64, i int) uint64 {
+	if r.nodeWeights[i] <= 0 {
+		return 0
+	}
+	return r.nodeHash(keyHash, i) >> 11
+}
+
+// computeWeight 加权 rendezvous 得分 (Schindelhauer, Schomaker, 2005; Resch)
+// 得分为 weight / -ln(u)，u 为哈希映射到 (0, 1) 的均匀值，节点被选中的概率与权重成正比
+func (r *RendezvousHash[T]) computeWeight(keyHash uint64, i int) float64 {
+	return utils.WeightedHashScore(r.nodeHash(keyHash, i), r.nodeWeights[i])
+}
+
+// refreshWeightClasses 节点或权重变化后重新按权重分组
+func (r *RendezvousHash[T]) refreshWeightClasses() {
+	r.weightClasses = r.weightClasses[:0]
+	r.nodeClasses = r.nodeClasses[:0]
+	for _, weight := range r.nodeWeights {
+		class := -1
+		for c, w := range r.weightClasses {
+			if w == weight {
+				class = c
+				break
+			}
+		}
+		if class < 0 {
+			if len(r.weightClasses) >= maxWeightClasses {
+				r.weightClasses = r.weightClasses[:0]
+				r.nodeClasses = r.nodeClasses[:0]
+				return
+			}
+			class = len(r.weightClasses)
+			r.weightClasses = append(r.weightClasses, weight)
+		}
+		r.nodeClasses = append(r.nodeClasses, uint8(class))
+	}
 }
 
 func (r *RendezvousHash[T]) AddNode(node T) {
@@ -36,6 +88,9 @@ func (r *RendezvousHash[T]) AddNode(node T) {
 	}
 	r.nodeMap[nodeKey] = struct{}{}
 	r.nodeList = append(r.nodeList, node)
+	r.nodeSeeds = append(r.nodeSeeds, r.hashFunc([]byte(nodeKey)))
+	r.nodeWeights = append(r.nodeWeights, node.GetWeight())
+	r.refreshWeightClasses()
 }
 
 func (r *RendezvousHash[T]) RemoveNode(node T) {
@@ -55,6 +110,9 @@ func (r *RendezvousHash[T]) RemoveNode(node T) {
 		return
 	}
 	r.nodeList = append(r.nodeList[:idx], r.nodeList[idx+1:]...)
+	r.nodeSeeds = append(r.nodeSeeds[:idx], r.nodeSeeds[idx+1:]...)
+	r.nodeWeights = append(r.nodeWeights[:idx], r.nodeWeights[idx+1:]...)
+	r.refreshWeightClasses()
 }
 
 func (r *RendezvousHash[T]) Get(key string) (T, error) {
@@ -62,18 +120,131 @@ func (r *RendezvousHash[T]) Get(key string) (T, error) {
 		var zero T
 		return zero, fmt.Errorf("nodeList is empty")
 	}
-	maxWeight := uint64(0)
-	var selectNode T
-	for _, node := range r.nodeList {
-		weight := r.computeWeight(key, node)
-		if weight > maxWeight {
-			maxWeight = weight
-			selectNode = node
+	keyHash := r.hashFunc(utils.StringToBytes(key))
+	if len(r.weightClasses) <= 0 {
+		return r.nodeList[r.maxScore(keyHash)], nil
+	}
+	// 所有节点权重相同且为正时只需比较哈希
+	if len(r.weightClasses) == 1 && r.weightClasses[0] > 0 {
+		best, bestHash := 0, r.nodeHash(keyHash, 0)>>11
+		for i := 1; i < len(r.nodeList); i++ {
+			hash := r.nodeHash(keyHash, i) >> 11
+			if hash > bestHash || (hash == bestHash && r.nodeList[i].GetKey() < r.nodeList[best].GetKey()) {
+				best, bestHash = i, hash
+			}
+		}
+		return r.nodeList[best], nil
+	}
+	// 每个权重分组内 (哈希, nodeKey) 最大的节点
+	var bests [maxWeightClasses]int
+	var bestHashes [maxWeightClasses]uint64
+	for c := range r.weightClasses {
+		bests[c] = -1
+	}
+	for i := range r.nodeList {
+		c := r.nodeClasses[i]
+		hash := r.scoreHash(keyHash, i)
+		if best := bests[c]; best < 0 || hash > bestHashes[c] ||
+			(hash == bestHashes[c] && r.nodeList[i].GetKey() < r.nodeList[best].GetKey()) {
+			bests[c], bestHashes[c] = i, hash
+		}
+	}
+	best := bests[0]
+	if len(r.weightClasses) > 1 {
+		maxWeight, maxHash := r.computeWeight(keyHash, best), bestHashes[0]
+		for c := 1; c < len(r.weightClasses); c++ {
+			weight := r.computeWeight(keyHash, bests[c])
+			if higherScore(weight, bestHashes[c], r.nodeList[bests[c]].GetKey(),
+				maxWeight, maxHash, r.nodeList[best].GetKey()) {
+				best, maxWeight, maxHash = bests[c], weight, bestHashes[c]
+			}
 		}
 	}
-	return selectNode, nil
+	return r.nodeList[best], nil
+}
+
+// maxScore 逐个计算得分，返回排在最前的节点下标
+func (r *RendezvousHash[T]) maxScore(keyHash uint64) int {
+	best := 0
+	maxWeight, maxHash := r.computeWeight(keyHash, 0), r.scoreHash(keyHash, 0)
+	for i := 1; i < len(r.nodeList); i++ {
+		weight, hash := r.computeWeight(keyHash, i), r.scoreHash(keyHash, i)
+		if higherScore(weight, hash, r.nodeList[i].GetKey(), maxWeight, maxHash, r.nodeList[best].GetKey()) {
+			best, maxWeight, maxHash = i, weight, hash
+		}
+	}
+	return best
+}
+
+// NodeScore 节点及其对某个key的得分
+type NodeScore[T models.HashNode] struct {
+	Node  T
+	Score float64
+	hash  uint64 // 得分相同时参与比较的哈希
+}
+
+// Rank 返回所有节点按得分从高到低排列的结果，与 Get 使用相同的比较顺序。
+// 第一个节点与 Get 一致，其余节点可作为有序的备选或副本
+func (r *RendezvousHash[T]) Rank(key string) []NodeScore[T] {
+	keyHash := r.hashFunc(utils.StringToBytes(key))
+	scores := make([]NodeScore[T], 0, len(r.nodeList))
+	for i, node := range r.nodeList {
+		scores = append(scores, NodeScore[T]{Node: node, Score: r.computeWeight(keyHash, i), hash: r.scoreHash(keyHash, i)})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return higherScore(scores[i].Score, scores[i].hash, scores[i].Node.GetKey(),
+			scores[j].Score, scores[j].hash, scores[j].Node.GetKey())
+	})
+	return scores
+}
+
+// GetN 获取得分最高的至多n个节点
+func (r *RendezvousHash[T]) GetN(key string, n int) ([]T, error) {
+	if len(r.nodeList) <= 0 {
+		return nil, fmt.Errorf("nodeList is empty")
+	}
+	scores := r.Rank(key)
+	n = max(min(n, len(scores)), 0)
+	nodes := make([]T, 0, n)
+	for _, score := range scores[:n] {
+		nodes = append(nodes, score.Node)
+	}
+	return nodes, nil
+}
+
+// higherScore 判断节点a是否排在节点b之前，得分相同时哈希较大的优先，再相同时nodeKey较小的优先
+func higherScore(scoreA float64, hashA uint64, keyA string, scoreB float64, hashB uint64, keyB string) bool {
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	if hashA != hashB {
+		return hashA > hashB
+	}
+	return keyA < keyB
 }
 
 func (r *RendezvousHash[T]) GetNodeCount() int {
 	return len(r.nodeList)
 }
+
+// UpdateNode 更新节点实例，新的权重在更新后生效
+func (r *RendezvousHash[T]) UpdateNode(node T) {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; !ok {
+		return
+	}
+	for i, n := range r.nodeList {
+		if n.GetKey() == nodeKey {
+			r.nodeList[i] = node
+			r.nodeWeights[i] = node.GetWeight()
+			r.refreshWeightClasses()
+			return
+		}
+	}
+}
+
+func (r *RendezvousHash[T]) GetNodes() []T {
+	nodes := make([]T, len(r.nodeList))
+	copy(nodes, r.nodeList)
+	return nodes
+}
@@ -0,0 +1,147 @@
+package rendezvous_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestRendezvousHash_WeightedShare(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 2, true),
+		models.NewNormalHashNode("node_3", 5, true),
+		models.NewNormalHashNode("node_4", 10, true),
+		models.NewNormalHashNode("node_5", 100, true),
+		models.NewNormalHashNode("node_6", 0, true),
+	}
+	obj := NewRendezvousHash(nodes, utils.GetHashCode)
+	keyCount := 500000
+	counts := make(map[string]int)
+	for i := 0; i < keyCount; i++ {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		counts[node.GetKey()]++
+	}
+	// 节点分到的key的比例与权重成正比，权重为0的节点不分配key
+	totalWeight := 118
+	for _, node := range nodes {
+		expected := float64(keyCount) * float64(node.GetWeight()) / float64(totalWeight)
+		tolerance := math.Max(expected*0.05, 100)
+		if math.Abs(float64(counts[node.GetKey()])-expected) > tolerance {
+			t.Fatalf("node %v got %v keys, want about %v", node.GetKey(), counts[node.GetKey()], expected)
+		}
+	}
+	if counts["node_6"] != 0 {
+		t.Fatalf("zero weight node got %v keys", counts["node_6"])
+	}
+}
+
+func TestRendezvousHash_Rank(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1+i%3, true))
+	}
+	obj := NewRendezvousHash(nodes, utils.GetHashCode)
+	for i := 0; i < 1000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		ranked := obj.Rank(key)
+		if len(ranked) != len(nodes) {
+			t.Fatalf("key %v ranked %v nodes, want %v", key, len(ranked), len(nodes))
+		}
+		// 得分从高到低排列，第一个节点与 Get 一致
+		for j := 1; j < len(ranked); j++ {
+			if ranked[j].Score > ranked[j-1].Score {
+				t.Fatalf("key %v not sorted by score", key)
+			}
+		}
+		primary, _ := obj.Get(key)
+		if ranked[0].Node.GetKey() != primary.GetKey() {
+			t.Fatalf("key %v rank first %v, want %v", key, ranked[0].Node.GetKey(), primary.GetKey())
+		}
+		replicas, err := obj.GetN(key, 3)
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		for j, node := range replicas {
+			if node.GetKey() != ranked[j].Node.GetKey() {
+				t.Fatalf("key %v replica %v is %v, want %v", key, j, node.GetKey(), ranked[j].Node.GetKey())
+			}
+		}
+	}
+	// 删除首选节点后，原来的第二个节点成为首选
+	key := "photoId_1"
+	ranked := obj.Rank(key)
+	obj.RemoveNode(ranked[0].Node)
+	if primary, _ := obj.Get(key); primary.GetKey() != ranked[1].Node.GetKey() {
+		t.Fatalf("primary %v, want %v", primary.GetKey(), ranked[1].Node.GetKey())
+	}
+	if replicas, _ := obj.GetN(key, 20); len(replicas) != len(nodes)-1 {
+		t.Fatalf("got %v replicas, want %v", len(replicas), len(nodes)-1)
+	}
+}
+
+func TestRendezvousHash_WeightClasses(t *testing.T) {
+	// 分别覆盖权重相同、按权重分组、权重种类过多逐个计算得分的情况
+	for _, classes := range []int{1, 3, 12} {
+		nodes := make([]models.HashNode, 0, 50)
+		for i := 0; i < 50; i++ {
+			nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1+i%classes, true))
+		}
+		obj := NewRendezvousHash(nodes, utils.GetHashCode)
+		for i := 0; i < 2000; i++ {
+			key := "key_" + strconv.Itoa(i)
+			node, _ := obj.Get(key)
+			if expected := obj.Rank(key)[0].Node; node.GetKey() != expected.GetKey() {
+				t.Fatalf("classes %v: key %v got %v, want %v", classes, key, node.GetKey(), expected.GetKey())
+			}
+		}
+	}
+}
+
+// unmix64 Mix64 的逆函数，用于构造指定的节点哈希
+func unmix64(x uint64) uint64 {
+	inverse := func(m uint64) uint64 {
+		// 牛顿迭代求奇数m模2^64的逆元
+		y := m
+		for i := 0; i < 5; i++ {
+			y *= 2 - m*y
+		}
+		return y
+	}
+	x ^= x>>31 ^ x>>62
+	x *= inverse(0x94d049bb133111eb)
+	x ^= x>>27 ^ x>>54
+	x *= inverse(0xbf58476d1ce4e5b9)
+	x ^= x>>30 ^ x>>60
+	return x
+}
+
+func TestRendezvousHash_ScoreTie(t *testing.T) {
+	// 哈希高53位相邻的两个节点，转为浮点数后得分相同，Get 与 GetN 需按同一顺序选出哈希较大的节点
+	hashA, hashB := uint64(1<<52+3)<<11, uint64(1<<52+4)<<11
+	if utils.WeightedHashScore(hashA, 1) != utils.WeightedHashScore(hashB, 1) {
+		t.Fatalf("scores should tie")
+	}
+	seeds := map[string]uint64{"key": 0, "node_a": unmix64(hashA), "node_b": unmix64(hashB), "node_c": 0}
+	hashFunc := func(data []byte) uint64 {
+		return seeds[string(data)]
+	}
+	for _, weights := range [][]int{{1, 1, 0}, {2, 2, 1}} {
+		nodes := []models.HashNode{
+			models.NewNormalHashNode("node_a", weights[0], true),
+			models.NewNormalHashNode("node_b", weights[1], true),
+			models.NewNormalHashNode("node_c", weights[2], true),
+		}
+		obj := NewRendezvousHash(nodes, hashFunc)
+		node, _ := obj.Get("key")
+		first, _ := obj.GetN("key", 1)
+		if node.GetKey() != "node_b" || first[0].GetKey() != node.GetKey() {
+			t.Fatalf("weights %v: Get %v, GetN %v, want node_b", weights, node.GetKey(), first[0].GetKey())
+		}
+	}
+}
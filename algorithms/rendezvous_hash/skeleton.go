@@ -0,0 +1,315 @@
+package rendezvous_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// 基于骨架的层次化 rendezvous 哈希 (Wang, Ravindran, 2004)
+// 1. 节点放在虚拟树的叶子槽位上，每个内部节点有fanout个子节点，内部节点的权重为其子树的权重之和
+// 2. 虚拟树按容量预先分配，未使用的槽位预留 slotWeight 的权重(取第一个加入的节点的权重)，
+//    在空槽位上添加相同权重的节点不会改变任何祖先节点的权重
+// 3. 每个槽位对 key 有一个到达时间，各槽位的到达时间相互独立且服从速率为槽位权重的指数分布，
+//    存活槽位中到达时间最早的节点被选中，与加权 rendezvous 相同，各节点分到的key与权重成正比。
+//    到达时间自顶向下生成: 子树的到达时间为其所有槽位中最早的一个，按子树权重选出继承该时间的子节点，
+//    其余子节点的到达时间在此基础上再加一个指数分布的间隔。最左侧路径(骨干)上节点的兄弟子树独立生成到达时间，
+//    扩容时原来的树成为新根的第一个子树，骨干向上延长，所有已有槽位的到达时间不变
+// 4. 查询时按到达时间的下界做最优优先搜索，跳过没有存活节点的子树，通常沿继承到达时间的子节点直接到达叶子，
+//    复杂度为 O(fanout * log(n))
+// 5. 删除节点时保留其槽位及权重(墓碑)，添加节点时优先复用最后被删除的槽位，否则占用下一个空槽位。
+//    槽位的到达时间只与槽位的位置及预留权重有关，因此只有被删除节点上的key迁移，或只有key迁移到新节点上，
+//    扩容也不例外，相同权重时重新加入后映射完全恢复
+// 6. 槽位分配依赖节点加入的顺序，初始节点按nodeKey排序后加入，不同进程按相同顺序变更节点即可得到相同的结果。
+//    节点权重与预留权重不同或修改权重时祖先节点的权重改变，少量其他key会随之迁移
+
+const (
+	defaultSkeletonFanout = 8
+	skeletonSplitSeed     = 0x9e3779b97f4a7c15 // 选取继承到达时间的子节点时使用的种子
+)
+
+// skeletonCandidate 最优优先搜索中待展开的子树。
+// skip不小于0时表示第level层第idx个节点除skip以外的子节点，time为它们到达时间的下界(父节点的到达时间)
+type skeletonCandidate struct {
+	time  float64
+	level int
+	idx   int
+	skip  int
+}
+
+// skeletonSlot 叶子槽位
+type skeletonSlot[T models.HashNode] struct {
+	node  T
+	alive bool // 槽位上的节点是否存在，删除后保留槽位作为墓碑
+}
+
+type HierarchicalRendezvousHash[T models.HashNode] struct {
+	fanout      int                 // 每个内部节点的子节点数
+	slots       []skeletonSlot[T]   // 叶子槽位
+	nodeMap     map[string]int      // 节点所在的槽位，key为nodeKey
+	freeSlots   []int               // 被删除节点的槽位，按删除顺序排列
+	slotWeight  int                 // 未使用的槽位预留的权重
+	weights     [][]int             // 每一层每个节点的权重，包含墓碑和未使用的槽位，第0层为叶子
+	liveWeights [][]int             // 每一层每个节点存活叶子的权重之和
+	hashFunc    func([]byte) uint64 // 哈希函数
+}
+
+// NewHierarchicalRendezvousHash 创建层次化 rendezvous 哈希，fanout不大于1时使用默认值8
+func NewHierarchicalRendezvousHash[T models.HashNode](nodeList []T, fanout int,
+	hashFunc func([]byte) uint64) *HierarchicalRendezvousHash[T] {
+	if fanout <= 1 {
+		fanout = defaultSkeletonFanout
+	}
+	obj := &HierarchicalRendezvousHash[T]{
+		fanout:   fanout,
+		nodeMap:  make(map[string]int),
+		hashFunc: hashFunc,
+	}
+	sorted := make([]T, len(nodeList))
+	copy(sorted, nodeList)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetKey() < sorted[j].GetKey()
+	})
+	for _, node := range sorted {
+		obj.AddNode(node)
+	}
+	return obj
+}
+
+// AddNode 添加节点，优先复用最后被删除的槽位，否则占用下一个空槽位
+func (r *HierarchicalRendezvousHash[T]) AddNode(node T) {
+	nodeKey := node.GetKey()
+	if _, ok := r.nodeMap[nodeKey]; ok {
+		return
+	}
+	var slot int
+	if n := len(r.freeSlots); n > 0 {
+		slot = r.freeSlots[n-1]
+		r.freeSlots = r.freeSlots[:n-1]
+		r.slots[slot] = skeletonSlot[T]{node: node, alive: true}
+	} else {
+		if r.slotWeight <= 0 {
+			r.slotWeight = max(node.GetWeight(), 1)
+		}
+		slot = len(r.slots)
+		r.slots = append(r.slots, skeletonSlot[T]{node: node, alive: true})
+		if len(r.weights) == 0 || slot >= len(r.weights[0]) {
+			r.grow()
+		}
+	}
+	r.nodeMap[nodeKey] = slot
+	r.updatePath(slot)
+}
+
+// RemoveNode 删除节点，槽位作为墓碑保留原有权重
+func (r *HierarchicalRendezvousHash[T]) RemoveNode(node T) {
+	nodeKey := node.GetKey()
+	slot, ok := r.nodeMap[nodeKey]
+	if !ok {
+		return
+	}
+	delete(r.nodeMap, nodeKey)
+	r.slots[slot].alive = false
+	r.freeSlots = append(r.freeSlots, slot)
+	r.updatePath(slot)
+}
+
+// UpdateNode 更新节点实例，权重变化时更新祖先节点的权重
+func (r *HierarchicalRendezvousHash[T]) UpdateNode(node T) {
+	slot, ok := r.nodeMap[node.GetKey()]
+	if !ok {
+		return
+	}
+	r.slots[slot].node = node
+	r.updatePath(slot)
+}
+
+// grow 槽位用完时在根之上增加一层，容量扩大为fanout倍，
+// 原来的树成为新根的第一个子树，新增的槽位预留 slotWeight 的权重
+func (r *HierarchicalRendezvousHash[T]) grow() {
+	if len(r.weights) == 0 {
+		r.weights = [][]int{{r.slotWeight}}
+		r.liveWeights = [][]int{{0}}
+		return
+	}
+	r.weights = append(r.weights, nil)
+	r.liveWeights = append(r.liveWeights, nil)
+	size := len(r.weights[0]) * r.fanout
+	for level := 0; level < len(r.weights); level++ {
+		for idx := len(r.weights[level]); idx < size; idx++ {
+			weightSum, liveSum := r.slotWeight, 0
+			if level > 0 {
+				weightSum, liveSum = r.sumChildren(level, idx)
+			}
+			r.weights[level] = append(r.weights[level], weightSum)
+			r.liveWeights[level] = append(r.liveWeights[level], liveSum)
+		}
+		size /= r.fanout
+	}
+}
+
+// updatePath 重新计算槽位到根路径上每个节点的权重
+func (r *HierarchicalRendezvousHash[T]) updatePath(slot int) {
+	// 墓碑保留原有权重，不参与存活权重
+	r.liveWeights[0][slot] = 0
+	if r.slots[slot].alive {
+		weight := max(r.slots[slot].node.GetWeight(), 0)
+		r.weights[0][slot] = weight
+		r.liveWeights[0][slot] = weight
+	}
+	idx := slot
+	for level := 1; level < len(r.weights); level++ {
+		idx /= r.fanout
+		r.weights[level][idx], r.liveWeights[level][idx] = r.sumChildren(level, idx)
+	}
+}
+
+// sumChildren 第level层第idx个节点的子节点的权重之和及存活权重之和
+func (r *HierarchicalRendezvousHash[T]) sumChildren(level, idx int) (int, int) {
+	from, to := r.children(level, idx)
+	weightSum, liveSum := 0, 0
+	for j := from; j < to; j++ {
+		weightSum += r.weights[level-1][j]
+		liveSum += r.liveWeights[level-1][j]
+	}
+	return weightSum, liveSum
+}
+
+// children 第level层第idx个节点的子节点在下一层中的范围 [from, to)
+func (r *HierarchicalRendezvousHash[T]) children(level, idx int) (int, int) {
+	from := idx * r.fanout
+	return from, min(from+r.fanout, len(r.weights[level-1]))
+}
+
+// nodeHash 虚拟树中第level层第idx个节点对keyHash的哈希，节点的位置决定其种子
+func (r *HierarchicalRendezvousHash[T]) nodeHash(keyHash uint64, level, idx int) uint64 {
+	return utils.Mix64(keyHash ^ utils.Mix64(uint64(level)<<48|uint64(idx)))
+}
+
+// arrivalDelay 速率为节点权重的指数分布的到达时间间隔
+func (r *HierarchicalRendezvousHash[T]) arrivalDelay(keyHash uint64, level, idx int) float64 {
+	return 1 / utils.WeightedHashScore(r.nodeHash(keyHash, level, idx), r.weights[level][idx])
+}
+
+// splitChild 按子节点的权重选出继承第level层第idx个节点到达时间的子节点
+func (r *HierarchicalRendezvousHash[T]) splitChild(keyHash uint64, level, idx int) int {
+	hash := utils.Mix64(r.nodeHash(keyHash, level, idx) ^ skeletonSplitSeed)
+	target, _ := bits.Mul64(hash, uint64(r.weights[level][idx]))
+	from, to := r.children(level, idx)
+	for j := from; j < to-1; j++ {
+		weight := uint64(r.weights[level-1][j])
+		if target < weight {
+			return j
+		}
+		target -= weight
+	}
+	return to - 1
+}
+
+func (r *HierarchicalRendezvousHash[T]) Get(key string) (T, error) {
+	var zero T
+	top := len(r.weights) - 1
+	if top < 0 || r.liveWeights[top][0] <= 0 {
+		return zero, fmt.Errorf("nodeList is empty")
+	}
+	keyHash := r.hashFunc(utils.StringToBytes(key))
+	var buf [64]skeletonCandidate
+	queue := skeletonQueue(buf[:0])
+	// 骨干上节点的兄弟子树独立生成到达时间，骨干的叶子为第0个槽位
+	for level := top; level > 0; level-- {
+		queue = r.pushChildren(queue, keyHash, level, 0, 0, 0)
+	}
+	if r.liveWeights[0][0] > 0 {
+		queue = queue.push(skeletonCandidate{r.arrivalDelay(keyHash, 0, 0), 0, 0, -1})
+	}
+	for {
+		var c skeletonCandidate
+		c, queue = queue.pop()
+		if c.skip >= 0 {
+			queue = r.pushChildren(queue, keyHash, c.level, c.idx, c.skip, c.time)
+			continue
+		}
+		// 沿继承到达时间的子节点向下走，其余子节点以当前到达时间为下界整体入队
+		for c.level > 0 {
+			next := r.splitChild(keyHash, c.level, c.idx)
+			if r.liveWeights[c.level][c.idx] > r.liveWeights[c.level-1][next] {
+				queue = queue.push(skeletonCandidate{c.time, c.level, c.idx, next})
+			}
+			if r.liveWeights[c.level-1][next] <= 0 {
+				break
+			}
+			c.level, c.idx = c.level-1, next
+		}
+		if c.level == 0 {
+			return r.slots[c.idx].node, nil
+		}
+	}
+}
+
+// pushChildren 计算第level层第idx个节点除skip以外的存活子节点的到达时间并入队
+func (r *HierarchicalRendezvousHash[T]) pushChildren(queue skeletonQueue, keyHash uint64, level, idx, skip int,
+	base float64) skeletonQueue {
+	from, to := r.children(level, idx)
+	for j := from; j < to; j++ {
+		if j != skip && r.liveWeights[level-1][j] > 0 {
+			queue = queue.push(skeletonCandidate{base + r.arrivalDelay(keyHash, level-1, j), level - 1, j, -1})
+		}
+	}
+	return queue
+}
+
+// skeletonQueue 按到达时间排列的小顶堆
+type skeletonQueue []skeletonCandidate
+
+func (q skeletonQueue) push(c skeletonCandidate) skeletonQueue {
+	items := append(q, c)
+	for i := len(items) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if items[parent].time <= items[i].time {
+			break
+		}
+		items[parent], items[i] = items[i], items[parent]
+		i = parent
+	}
+	return items
+}
+
+func (q skeletonQueue) pop() (skeletonCandidate, skeletonQueue) {
+	items := q
+	top := items[0]
+	last := len(items) - 1
+	items[0] = items[last]
+	items = items[:last]
+	for i := 0; ; {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(items) && items[left].time < items[smallest].time {
+			smallest = left
+		}
+		if right < len(items) && items[right].time < items[smallest].time {
+			smallest = right
+		}
+		if smallest == i {
+			break
+		}
+		items[i], items[smallest] = items[smallest], items[i]
+		i = smallest
+	}
+	return top, items
+}
+
+func (r *HierarchicalRendezvousHash[T]) GetNodeCount() int {
+	return len(r.nodeMap)
+}
+
+// GetNodes 按槽位的顺序返回所有节点
+func (r *HierarchicalRendezvousHash[T]) GetNodes() []T {
+	nodes := make([]T, 0, len(r.nodeMap))
+	for _, slot := range r.slots {
+		if slot.alive {
+			nodes = append(nodes, slot.node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,191 @@
+package rendezvous_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHierarchicalRendezvousHash_Distribution(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1+i%4, true))
+	}
+	obj := NewHierarchicalRendezvousHash(nodes, 8, utils.GetHashCode)
+	// 节点加入的顺序不影响结果
+	reversed := make([]models.HashNode, 0, len(nodes))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		reversed = append(reversed, nodes[i])
+	}
+	other := NewHierarchicalRendezvousHash(reversed, 8, utils.GetHashCode)
+
+	keyCount := 1000000
+	counts := make(map[string]int)
+	for i := 0; i < keyCount; i++ {
+		key := "key_" + strconv.Itoa(i)
+		node, err := obj.Get(key)
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		if i%10 == 0 {
+			if n, _ := other.Get(key); n.GetKey() != node.GetKey() {
+				t.Fatalf("key %v got %v and %v", key, node.GetKey(), n.GetKey())
+			}
+		}
+		counts[node.GetKey()]++
+	}
+	// 每种权重的节点分到的key的比例与权重成正比
+	weightCounts := make(map[int]int)
+	for _, node := range nodes {
+		weightCounts[node.GetWeight()] += counts[node.GetKey()]
+	}
+	for weight, count := range weightCounts {
+		expected := float64(keyCount) * float64(weight*250) / 2500
+		if math.Abs(float64(count)-expected) > expected*0.03 {
+			t.Fatalf("weight %v got %v keys, want about %v", weight, count, expected)
+		}
+	}
+}
+
+func TestHierarchicalRendezvousHash_AddNode(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 1010)
+	for i := 0; i < 1010; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewHierarchicalRendezvousHash(nodes[:1000], 8, utils.GetHashCode)
+	keyCount := 200000
+	before := make([]string, keyCount)
+	for i := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		before[i] = node.GetKey()
+	}
+	// 添加节点后，key只会迁移到新节点上
+	added := make(map[string]struct{})
+	for _, node := range nodes[1000:] {
+		obj.AddNode(node)
+		added[node.GetKey()] = struct{}{}
+	}
+	moved := 0
+	for i, nodeKey := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		if node.GetKey() == nodeKey {
+			continue
+		}
+		if _, ok := added[node.GetKey()]; !ok {
+			t.Fatalf("key_%v moved from %v to %v", i, nodeKey, node.GetKey())
+		}
+		moved++
+	}
+	// 迁移的key的比例约为新节点的权重占比
+	expected := float64(keyCount) * 10 / 1010
+	if math.Abs(float64(moved)-expected) > expected*0.1 {
+		t.Fatalf("%v keys moved, want about %v", moved, expected)
+	}
+}
+
+func TestHierarchicalRendezvousHash_Grow(t *testing.T) {
+	// 4096个槽位用完后扩容，key只会迁移到新节点上
+	nodes := make([]models.HashNode, 0, 4106)
+	for i := 0; i < 4106; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewHierarchicalRendezvousHash(nodes[:4090], 8, utils.GetHashCode)
+	keyCount := 200000
+	before := make([]string, keyCount)
+	for i := range before {
+		node, err := obj.Get("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		before[i] = node.GetKey()
+	}
+	added := make(map[string]struct{})
+	for _, node := range nodes[4090:] {
+		obj.AddNode(node)
+		added[node.GetKey()] = struct{}{}
+	}
+	moved := 0
+	for i, nodeKey := range before {
+		node, _ := obj.Get("key_" + strconv.Itoa(i))
+		if node.GetKey() == nodeKey {
+			continue
+		}
+		if _, ok := added[node.GetKey()]; !ok {
+			t.Fatalf("key_%v moved from %v to %v", i, nodeKey, node.GetKey())
+		}
+		moved++
+	}
+	expected := float64(keyCount) * 16 / 4106
+	if math.Abs(float64(moved)-expected) > expected*0.15 {
+		t.Fatalf("%v keys moved, want about %v", moved, expected)
+	}
+	// 删除新节点后映射完全恢复
+	for _, node := range nodes[4090:] {
+		obj.RemoveNode(node)
+	}
+	for i, nodeKey := range before {
+		if node, _ := obj.Get("key_" + strconv.Itoa(i)); node.GetKey() != nodeKey {
+			t.Fatalf("key_%v mapped to %v, want %v", i, node.GetKey(), nodeKey)
+		}
+	}
+}
+
+func TestHierarchicalRendezvousHash_RemoveNode(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 100)
+	for i := 0; i < 100; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewHierarchicalRendezvousHash(nodes, 4, utils.GetHashCode)
+	before := make(map[string]string)
+	for i := 0; i < 50000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		node, _ := obj.Get(key)
+		before[key] = node.GetKey()
+	}
+	// 删除节点后，只有被删除节点上的key发生迁移
+	removed := map[string]struct{}{"node_3": {}, "node_42": {}, "node_43": {}}
+	for _, node := range nodes {
+		if _, ok := removed[node.GetKey()]; ok {
+			obj.RemoveNode(node)
+		}
+	}
+	for key, nodeKey := range before {
+		node, err := obj.Get(key)
+		if err != nil {
+			t.Fatalf("rendezvous hash err: %v", err)
+		}
+		if _, ok := removed[node.GetKey()]; ok {
+			t.Fatalf("key %v got removed node %v", key, node.GetKey())
+		}
+		if _, ok := removed[nodeKey]; !ok && node.GetKey() != nodeKey {
+			t.Fatalf("key %v moved from %v to %v", key, nodeKey, node.GetKey())
+		}
+	}
+	if obj.GetNodeCount() != 97 || len(obj.GetNodes()) != 97 {
+		t.Fatalf("node count %v, want 97", obj.GetNodeCount())
+	}
+	// 重新加入后映射完全恢复
+	for _, nodeKey := range []string{"node_43", "node_42", "node_3"} {
+		obj.AddNode(models.NewNormalHashNode(nodeKey, 1, true))
+	}
+	for key, nodeKey := range before {
+		if node, _ := obj.Get(key); node.GetKey() != nodeKey {
+			t.Fatalf("key %v mapped to %v, want %v", key, node.GetKey(), nodeKey)
+		}
+	}
+	// 删除所有节点
+	for _, node := range obj.GetNodes() {
+		obj.RemoveNode(node)
+	}
+	if _, err := obj.Get("key"); err == nil {
+		t.Fatalf("empty rendezvous hash should return error")
+	}
+}
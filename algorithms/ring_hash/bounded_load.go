@@ -0,0 +1,136 @@
+package ring_hash
+
+import (
+	"fmt"
+	"math"
+)
+
+// 有界负载一致性哈希 (Mirrokni, Thorup, Zadimoghaddam, 2016)
+// 每个节点的容量为 ceil(c * 平均负载)，查询时顺时针跳过已满的节点，
+// 大部分key仍落在原节点上，同时保证没有节点的负载超过平均值的c倍。
+// 负载的读写以及 AddNodes、RemoveNodes、UpdateNode 对节点表与环的整个修改都在 loadLock 下进行，
+// 因此 GetBounded、GetBoundedAndAcquire、Acquire、Release 之间以及与节点变更之间都可以并发调用。
+// 容量按最近一次加入或 UpdateNode 时的启用节点数计算，节点的启用状态改变后应调用 UpdateNode
+
+// SetLoadFactor 设置有界负载系数c，c必须不小于1；c为0时关闭负载限制
+func (r *RingHash[T]) SetLoadFactor(c float64) error {
+	if c != 0 && c < 1 {
+		return fmt.Errorf("load factor must be at least 1, got %v", c)
+	}
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	r.loadFactor = c
+	return nil
+}
+
+// GetBounded 获取key对应的负载未满的节点，调用方在分配请求后通过 Acquire 增加节点负载。
+// 检查与增加负载不是原子的，并发分配请求时应使用 GetBoundedAndAcquire
+func (r *RingHash[T]) GetBounded(key string) (T, error) {
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	return r.getBounded(key)
+}
+
+// GetBoundedAndAcquire 获取key对应的负载未满的节点并将其负载加1，
+// 检查与增加负载在同一次加锁内完成，并发分配请求时节点负载也不会超过容量
+func (r *RingHash[T]) GetBoundedAndAcquire(key string) (T, error) {
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	node, err := r.getBounded(key)
+	if err != nil {
+		return node, err
+	}
+	r.loadMap[node.GetKey()]++
+	r.totalLoad++
+	return node, nil
+}
+
+// getBounded 顺时针查找第一个负载未满的启用节点，调用方需持有 loadLock
+func (r *RingHash[T]) getBounded(key string) (T, error) {
+	var zero T
+	if len(r.nodeMap) <= 0 {
+		return zero, fmt.Errorf("ring is empty")
+	}
+	capacity := r.capacity()
+	// 无需从环上取
+	if len(r.nodeMap) <= r.ringFloorLimit {
+		for _, node := range r.rendezvousOrder(r.hashFunc([]byte(key))) {
+			if node.IsEnabled() && r.loadMap[node.GetKey()] < capacity {
+				return node, nil
+			}
+		}
+		return zero, fmt.Errorf("all nodes are at capacity")
+	}
+	var result T
+	found := false
+	r.walk(r.hashFunc([]byte(key)), func(node T) bool {
+		if node.IsEnabled() && r.loadMap[node.GetKey()] < capacity {
+			result = node
+			found = true
+			return false
+		}
+		return true
+	})
+	if !found {
+		return zero, fmt.Errorf("all nodes are at capacity")
+	}
+	return result, nil
+}
+
+// capacity 计算新请求加入后每个启用节点的容量，调用方需持有 loadLock
+func (r *RingHash[T]) capacity() int64 {
+	if r.loadFactor == 0 {
+		return math.MaxInt64
+	}
+	if r.enabledCount <= 0 {
+		return 0
+	}
+	avgLoad := float64(r.totalLoad+1) / float64(r.enabledCount)
+	return int64(math.Ceil(r.loadFactor * avgLoad))
+}
+
+// Acquire 节点负载加1，节点不存在时忽略
+func (r *RingHash[T]) Acquire(nodeKey string) {
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	if _, ok := r.nodeMap[nodeKey]; !ok {
+		return
+	}
+	r.loadMap[nodeKey]++
+	r.totalLoad++
+}
+
+// Release 节点负载减1
+func (r *RingHash[T]) Release(nodeKey string) {
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	if r.loadMap[nodeKey] <= 0 {
+		return
+	}
+	r.loadMap[nodeKey]--
+	r.totalLoad--
+}
+
+// GetLoad 获取节点当前负载
+func (r *RingHash[T]) GetLoad(nodeKey string) int64 {
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	return r.loadMap[nodeKey]
+}
+
+// GetLoads 获取所有节点的当前负载
+func (r *RingHash[T]) GetLoads() map[string]int64 {
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	loads := make(map[string]int64, len(r.loadMap))
+	for nodeKey, load := range r.loadMap {
+		loads[nodeKey] = load
+	}
+	return loads
+}
+
+// clearLoad 节点移除时清理其负载，调用方需持有 loadLock
+func (r *RingHash[T]) clearLoad(nodeKey string) {
+	r.totalLoad -= r.loadMap[nodeKey]
+	delete(r.loadMap, nodeKey)
+}
@@ -0,0 +1,98 @@
+package ring_hash
+
+import (
+	"consistent-hash/models"
+	"strings"
+)
+
+// 故障域感知的副本选择
+// 按标签从大到小(如 zone、rack)逐级选取: 先顺时针选取所在 zone 未被使用的节点，
+// zone 用尽后再选取所在 zone/rack 未被使用的节点，所有故障域都用尽后按顺时针顺序补齐。
+// 第一个副本总是顺时针方向的第一个启用节点，与不设置故障域时一致
+
+// SetFailureDomainLabels 设置故障域标签，按从大到小的顺序排列，如 "zone", "rack"。
+// 节点需实现 models.LabeledHashNode，未实现的节点视为所有标签为空；不传参数时关闭故障域感知
+func (r *RingHash[T]) SetFailureDomainLabels(labels ...string) {
+	r.failureDomainLabels = append([]string(nil), labels...)
+}
+
+// GetFailureDomainLabels 获取故障域标签
+func (r *RingHash[T]) GetFailureDomainLabels() []string {
+	return append([]string(nil), r.failureDomainLabels...)
+}
+
+// failureDomains 节点在每一级的故障域，第i级为前i+1个标签值的组合
+func (r *RingHash[T]) failureDomains(node T) []string {
+	domains := make([]string, len(r.failureDomainLabels))
+	labeled, ok := any(node).(models.LabeledHashNode)
+	var sb strings.Builder
+	for i, label := range r.failureDomainLabels {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		if ok {
+			sb.WriteString(labeled.GetLabel(label))
+		}
+		domains[i] = sb.String()
+	}
+	return domains
+}
+
+// collect 按候选顺序收集至多number个不重复的启用节点，设置了故障域时逐级分散副本
+func (r *RingHash[T]) collect(iterate func(visit func(node T) bool), number int) []T {
+	results := make([]T, 0, number)
+	if number <= 0 {
+		return results
+	}
+	// 只取一个节点时无需去重，第一个启用的节点总能被选中
+	if number == 1 {
+		iterate(func(node T) bool {
+			if node.IsEnabled() {
+				results = append(results, node)
+				return false
+			}
+			return true
+		})
+		return results
+	}
+	seen := make(map[string]struct{})
+	levels := len(r.failureDomainLabels)
+	// 每一级已使用的故障域
+	used := make([]map[string]struct{}, levels)
+	for i := range used {
+		used[i] = make(map[string]struct{})
+	}
+	domainCache := make(map[string][]string)
+	// 第level轮只接受该级故障域未被使用的节点，最后一轮不做限制
+	for level := 0; level <= levels && len(results) < number; level++ {
+		iterate(func(node T) bool {
+			if !node.IsEnabled() {
+				return true
+			}
+			nodeKey := node.GetKey()
+			if _, ok := seen[nodeKey]; ok {
+				return true
+			}
+			var domains []string
+			if levels > 0 {
+				var ok bool
+				if domains, ok = domainCache[nodeKey]; !ok {
+					domains = r.failureDomains(node)
+					domainCache[nodeKey] = domains
+				}
+			}
+			if level < levels {
+				if _, ok := used[level][domains[level]]; ok {
+					return true
+				}
+			}
+			seen[nodeKey] = struct{}{}
+			results = append(results, node)
+			for i, domain := range domains {
+				used[i][domain] = struct{}{}
+			}
+			return len(results) < number
+		})
+	}
+	return results
+}
@@ -0,0 +1,82 @@
+package ring_hash
+
+import (
+	"consistent-hash/models"
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// libmemcached ketama 兼容模式
+// 1. 每个节点生成若干组虚拟节点，第i组对 "nodeKey-i" 做 MD5，每个摘要切分出 4 个 32 位的哈希点
+// 2. 每个节点的组数按 libmemcached 的加权公式以单精度浮点计算: floor(权重占比 * 160 / 4 * 节点数 + 1e-10)，
+//    权重相同时也不例外，如 25 个相同权重的节点每个只有 39 组(156 个哈希点)
+// 3. key 的哈希为 MD5 摘要的前 4 个字节(小端)，环的范围为 [0, 2^32)
+// nodeKey 需与其他客户端使用的服务器地址一致，如 "host:port"。
+// 注意 libmemcached 对默认端口 11211 的服务器只使用 "host"，此时 nodeKey 也应只填 host
+
+const (
+	ketamaPointsPerServer = 160 // 每个节点的哈希点数
+	ketamaPointsPerHash   = 4   // 每个 MD5 摘要切分出的哈希点数
+)
+
+// NewKetamaRingHash 创建与 libmemcached ketama 兼容的哈希环
+func NewKetamaRingHash[T models.HashNode](nodes []T) *RingHash[T] {
+	obj := &RingHash[T]{
+		vnodeBaseNum:   ketamaPointsPerServer / ketamaPointsPerHash,
+		ringFloorLimit: 0,
+		nodeMap:        make(map[string]T),
+		nodeVnodeNum:   make(map[string]int),
+		nodeIndexMap:   make(map[string]uint32),
+		hashFunc:       KetamaHash,
+		ketama:         true,
+		loadMap:        make(map[string]int64),
+	}
+	obj.AddNodes(nodes)
+	return obj
+}
+
+// KetamaHash key 的 ketama 哈希，取 MD5 摘要的前 4 个字节(小端)
+func KetamaHash(key []byte) uint64 {
+	digest := md5.Sum(key)
+	return uint64(binary.LittleEndian.Uint32(digest[0:4]))
+}
+
+// generateKetamaPoints 生成节点第[from, to)组的哈希点，每组 4 个
+func generateKetamaPoints(nodeKey string, from, to int) []uint64 {
+	points := make([]uint64, 0, max(to-from, 0)*ketamaPointsPerHash)
+	for i := from; i < to; i++ {
+		digest := md5.Sum([]byte(nodeKey + "-" + strconv.Itoa(i)))
+		for h := 0; h < ketamaPointsPerHash; h++ {
+			points = append(points, uint64(binary.LittleEndian.Uint32(digest[h*4:h*4+4])))
+		}
+	}
+	return points
+}
+
+// rebalanceKetama 节点数或权重变化后重新计算每个节点的组数，只增删差值部分的哈希点
+func (r *RingHash[T]) rebalanceKetama() {
+	totalWeight := 0
+	for _, node := range r.nodeMap {
+		totalWeight += max(node.GetWeight(), 0)
+	}
+	deleted := make(map[vnode]int)
+	added := make([]vnode, 0)
+	for _, node := range r.nodeMap {
+		groups := ketamaWeightedGroups(max(node.GetWeight(), 0), totalWeight, len(r.nodeMap))
+		added = r.resizeVNodes(node.GetKey(), groups, added, deleted)
+	}
+	r.deleteVNodeHashKeys(deleted)
+	r.mergeVNodes(added)
+}
+
+// ketamaWeightedGroups 按 libmemcached 的单精度浮点运算计算加权后的组数
+func ketamaWeightedGroups(weight, totalWeight, serverNum int) int {
+	if totalWeight <= 0 {
+		return 0
+	}
+	pct := float32(weight) / float32(totalWeight)
+	points := pct * float32(ketamaPointsPerServer) / float32(ketamaPointsPerHash) * float32(serverNum)
+	return int(math.Floor(float64(float32(float64(points) + 0.0000000001))))
+}
@@ -0,0 +1,245 @@
+package ring_hash
+
+import (
+	"consistent-hash/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// 第三方黄金向量 testdata/gocbcore_memd_4node.exp.json 原样取自 Couchbase Go SDK
+// gopkg.in/couchbase/gocbcore.v7@v7.1.18 的 testdata/memd_4node.exp.json(Apache-2.0)，
+// 是其 memcached 桶的 ketama 路由期望结果，该 continuum 移植自 libcouchbase 的 lcbvb_CONTINUUM，
+// 与 libmemcached 的相同权重 ketama 一致: 每个节点 40 组 "host:port-i" 的 MD5，每组 4 个小端哈希点。
+// index 为节点在按字典序排序后的服务器列表中的下标，服务器列表由 memd_4node.config.json 与
+// 测试使用的源地址 localhost 得出
+var gocbcoreMemd4NodeServers = []string{
+	"10.0.0.195:12000",
+	"localhost:12002",
+	"localhost:12004",
+	"localhost:12006",
+}
+
+// 以下向量由 testdata/ketama_vectors.c 生成，该程序逐段移植 libmemcached hosts.cc 的
+// update_continuum(ketama weighted 分支) 与 libhashkit 的 MD5 哈希，用于覆盖第三方向量没有的加权与
+// 单精度浮点截断场景，它与本包的实现相互独立，但并非 libmemcached 本身的运行结果。
+// 服务器使用非默认端口 11212，libmemcached 以 "host:port-i" 计算哈希点，与 nodeKey "host:port" 一致
+var ketamaUniformVectors = [][2]string{
+	{"", "10.0.1.2:11212"},
+	{"a", "10.0.1.3:11212"},
+	{"foo", "10.0.1.2:11212"},
+	{"bar", "10.0.1.1:11212"},
+	{"hello", "10.0.1.2:11212"},
+	{"user:1", "10.0.1.2:11212"},
+	{"user:2", "10.0.1.2:11212"},
+	{"user:3", "10.0.1.1:11212"},
+	{"session:abcdef", "10.0.1.2:11212"},
+	{"photoId_1", "10.0.1.1:11212"},
+	{"key_0", "10.0.1.1:11212"},
+	{"key_1", "10.0.1.2:11212"},
+	{"key_2", "10.0.1.2:11212"},
+	{"key_3", "10.0.1.3:11212"},
+	{"key_4", "10.0.1.2:11212"},
+	{"key_5", "10.0.1.3:11212"},
+	{"key_6", "10.0.1.1:11212"},
+	{"key_7", "10.0.1.3:11212"},
+	{"key_8", "10.0.1.1:11212"},
+	{"key_9", "10.0.1.1:11212"},
+	{"memcached", "10.0.1.3:11212"},
+	{"ketama", "10.0.1.2:11212"},
+	{"libmemcached", "10.0.1.3:11212"},
+	{"consistent-hash", "10.0.1.1:11212"},
+	{"the quick brown fox", "10.0.1.2:11212"},
+}
+
+var ketamaWeightedVectors = [][2]string{
+	{"", "10.0.1.2:11212"},
+	{"a", "10.0.1.3:11212"},
+	{"foo", "10.0.1.2:11212"},
+	{"bar", "10.0.1.4:11212"},
+	{"hello", "10.0.1.2:11212"},
+	{"user:1", "10.0.1.2:11212"},
+	{"user:2", "10.0.1.4:11212"},
+	{"user:3", "10.0.1.1:11212"},
+	{"session:abcdef", "10.0.1.2:11212"},
+	{"photoId_1", "10.0.1.1:11212"},
+	{"key_0", "10.0.1.1:11212"},
+	{"key_1", "10.0.1.3:11212"},
+	{"key_2", "10.0.1.2:11212"},
+	{"key_3", "10.0.1.4:11212"},
+	{"key_4", "10.0.1.4:11212"},
+	{"key_5", "10.0.1.3:11212"},
+	{"key_6", "10.0.1.3:11212"},
+	{"key_7", "10.0.1.3:11212"},
+	{"key_8", "10.0.1.1:11212"},
+	{"key_9", "10.0.1.1:11212"},
+	{"memcached", "10.0.1.3:11212"},
+	{"ketama", "10.0.1.2:11212"},
+	{"libmemcached", "10.0.1.3:11212"},
+	{"consistent-hash", "10.0.1.4:11212"},
+	{"the quick brown fox", "10.0.1.2:11212"},
+}
+
+// ketamaEqualWeight25Vectors 25个相同权重的节点，单精度浮点运算使每个节点只有 39 组(156个)哈希点
+var ketamaEqualWeight25Vectors = [][2]string{
+	{"", "10.0.2.15:11212"},
+	{"a", "10.0.2.20:11212"},
+	{"foo", "10.0.2.10:11212"},
+	{"bar", "10.0.2.9:11212"},
+	{"hello", "10.0.2.23:11212"},
+	{"user:1", "10.0.2.8:11212"},
+	{"user:2", "10.0.2.21:11212"},
+	{"user:3", "10.0.2.23:11212"},
+	{"session:abcdef", "10.0.2.16:11212"},
+	{"photoId_1", "10.0.2.15:11212"},
+	{"key_0", "10.0.2.20:11212"},
+	{"key_1", "10.0.2.21:11212"},
+	{"key_2", "10.0.2.3:11212"},
+	{"key_3", "10.0.2.20:11212"},
+	{"key_4", "10.0.2.24:11212"},
+	{"key_5", "10.0.2.19:11212"},
+	{"key_6", "10.0.2.10:11212"},
+	{"key_7", "10.0.2.12:11212"},
+	{"key_8", "10.0.2.21:11212"},
+	{"key_9", "10.0.2.11:11212"},
+	{"memcached", "10.0.2.22:11212"},
+	{"ketama", "10.0.2.24:11212"},
+	{"libmemcached", "10.0.2.10:11212"},
+	{"consistent-hash", "10.0.2.16:11212"},
+	{"the quick brown fox", "10.0.2.5:11212"},
+}
+
+func TestKetamaRingHash_GocbcoreVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/gocbcore_memd_4node.exp.json")
+	if err != nil {
+		t.Fatalf("read vectors err: %v", err)
+	}
+	expected := make(map[string]struct {
+		Hash  uint32 `json:"hash"`
+		Index int    `json:"index"`
+	})
+	if err = json.Unmarshal(data, &expected); err != nil {
+		t.Fatalf("parse vectors err: %v", err)
+	}
+	if len(expected) == 0 {
+		t.Fatal("no vectors")
+	}
+	nodes := make([]models.HashNode, 0, len(gocbcoreMemd4NodeServers))
+	for _, server := range gocbcoreMemd4NodeServers {
+		nodes = append(nodes, models.NewNormalHashNode(server, 1, true))
+	}
+	obj := NewKetamaRingHash(nodes)
+	if obj.GetSortedKeyCount() != 160*4 {
+		t.Fatalf("point count %v, want %v", obj.GetSortedKeyCount(), 160*4)
+	}
+	for key, want := range expected {
+		if hash := KetamaHash([]byte(key)); hash != uint64(want.Hash) {
+			t.Errorf("key %q hash %v, want %v", key, hash, want.Hash)
+		}
+		results, err := obj.Get(key, 1)
+		if err != nil {
+			t.Fatalf("ketama get err: %v", err)
+		}
+		if results[0].GetKey() != gocbcoreMemd4NodeServers[want.Index] {
+			t.Errorf("key %q got %v, want %v", key, results[0].GetKey(), gocbcoreMemd4NodeServers[want.Index])
+		}
+	}
+}
+
+func TestKetamaRingHash_GoldenVectors(t *testing.T) {
+	cases := []struct {
+		name       string
+		nodes      []models.HashNode
+		pointCount int
+		vectors    [][2]string
+	}{
+		{
+			name: "uniform",
+			nodes: []models.HashNode{
+				models.NewNormalHashNode("10.0.1.1:11212", 1, true),
+				models.NewNormalHashNode("10.0.1.2:11212", 1, true),
+				models.NewNormalHashNode("10.0.1.3:11212", 1, true),
+			},
+			pointCount: 480,
+			vectors:    ketamaUniformVectors,
+		},
+		{
+			name: "weighted",
+			nodes: []models.HashNode{
+				models.NewNormalHashNode("10.0.1.1:11212", 100, true),
+				models.NewNormalHashNode("10.0.1.2:11212", 200, true),
+				models.NewNormalHashNode("10.0.1.3:11212", 300, true),
+				models.NewNormalHashNode("10.0.1.4:11212", 150, true),
+			},
+			pointCount: 636,
+			vectors:    ketamaWeightedVectors,
+		},
+		{
+			name:       "equal_weight_25",
+			nodes:      newKetamaTestNodes("10.0.2.%d:11212", 25),
+			pointCount: 3900,
+			vectors:    ketamaEqualWeight25Vectors,
+		},
+	}
+	for _, c := range cases {
+		obj := NewKetamaRingHash(c.nodes)
+		if obj.GetSortedKeyCount() != c.pointCount {
+			t.Fatalf("%s: point count %v, want %v", c.name, obj.GetSortedKeyCount(), c.pointCount)
+		}
+		for _, vector := range c.vectors {
+			results, err := obj.Get(vector[0], 1)
+			if err != nil {
+				t.Fatalf("%s: ketama get err: %v", c.name, err)
+			}
+			if results[0].GetKey() != vector[1] {
+				t.Errorf("%s: key %q got %v, want %v", c.name, vector[0], results[0].GetKey(), vector[1])
+			}
+		}
+	}
+}
+
+func TestKetamaRingHash_Membership(t *testing.T) {
+	// 逐个添加节点与一次性建环的结果一致
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("10.0.1.1:11212", 100, true),
+		models.NewNormalHashNode("10.0.1.2:11212", 200, true),
+		models.NewNormalHashNode("10.0.1.3:11212", 300, true),
+		models.NewNormalHashNode("10.0.1.4:11212", 150, true),
+	}
+	obj := NewKetamaRingHash(nodes[:1])
+	for _, node := range nodes[1:] {
+		obj.AddNode(node)
+	}
+	if obj.GetSortedKeyCount() != 636 {
+		t.Fatalf("point count %v, want 636", obj.GetSortedKeyCount())
+	}
+	for _, vector := range ketamaWeightedVectors {
+		results, _ := obj.Get(vector[0], 1)
+		if results[0].GetKey() != vector[1] {
+			t.Errorf("key %q got %v, want %v", vector[0], results[0].GetKey(), vector[1])
+		}
+	}
+	// 权重统一后回到每个节点 160 个哈希点
+	for _, node := range nodes {
+		obj.UpdateNode(models.NewNormalHashNode(node.GetKey(), 1, true))
+	}
+	obj.RemoveNode(nodes[3])
+	if obj.GetSortedKeyCount() != 480 {
+		t.Fatalf("point count %v, want 480", obj.GetSortedKeyCount())
+	}
+	for _, vector := range ketamaUniformVectors {
+		results, _ := obj.Get(vector[0], 1)
+		if results[0].GetKey() != vector[1] {
+			t.Errorf("key %q got %v, want %v", vector[0], results[0].GetKey(), vector[1])
+		}
+	}
+}
+
+func newKetamaTestNodes(format string, count int) []models.HashNode {
+	nodes := make([]models.HashNode, 0, count)
+	for i := 1; i <= count; i++ {
+		nodes = append(nodes, models.NewNormalHashNode(fmt.Sprintf(format, i), 1, true))
+	}
+	return nodes
+}
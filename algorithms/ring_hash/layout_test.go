@@ -0,0 +1,160 @@
+package ring_hash
+
+import (
+	"consistent-hash/models"
+	"consistent-hash/utils"
+	"runtime"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// legacyRing 旧的存储布局: 已排序的哈希数组 + 哈希到节点的 map，仅用于对比
+type legacyRing struct {
+	vnodeSortedList []uint64
+	vnodeHashMap    map[uint64]models.HashNode
+}
+
+func newLegacyRing(vnodeBaseNum int, nodes []models.HashNode) *legacyRing {
+	obj := &legacyRing{
+		vnodeSortedList: make([]uint64, 0),
+		vnodeHashMap:    make(map[uint64]models.HashNode),
+	}
+	for _, node := range nodes {
+		for i := 0; i < node.GetWeight()*vnodeBaseNum; i++ {
+			hashCode := utils.GetHashCode([]byte(node.GetKey() + "_" + strconv.Itoa(i)))
+			if _, ok := obj.vnodeHashMap[hashCode]; ok {
+				continue
+			}
+			obj.vnodeHashMap[hashCode] = node
+			obj.vnodeSortedList = append(obj.vnodeSortedList, hashCode)
+		}
+	}
+	sort.Slice(obj.vnodeSortedList, func(i, j int) bool {
+		return obj.vnodeSortedList[i] < obj.vnodeSortedList[j]
+	})
+	return obj
+}
+
+func (r *legacyRing) get(key string) models.HashNode {
+	hashCode := utils.GetHashCode([]byte(key))
+	idx := sort.Search(len(r.vnodeSortedList), func(i int) bool {
+		return r.vnodeSortedList[i] >= hashCode
+	})
+	if idx == len(r.vnodeSortedList) {
+		idx = 0
+	}
+	return r.vnodeHashMap[r.vnodeSortedList[idx]]
+}
+
+func benchmarkNodes(count int) []models.HashNode {
+	nodes := make([]models.HashNode, 0, count)
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	return nodes
+}
+
+func benchmarkKeys(count int) []string {
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = "key_" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+// heapInUse GC 后的堆内存占用
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func TestRingHash_Layout(t *testing.T) {
+	nodes := benchmarkNodes(100)
+	obj := NewRingHash(160, 1, nodes, utils.GetHashCode)
+	legacy := newLegacyRing(160, nodes)
+	if obj.GetSortedKeyCount() != len(legacy.vnodeSortedList) {
+		t.Fatalf("vnode count %v, want %v", obj.GetSortedKeyCount(), len(legacy.vnodeSortedList))
+	}
+	// 新布局与旧布局的查询结果一致
+	for _, key := range benchmarkKeys(10000) {
+		results, _ := obj.Get(key, 1)
+		if results[0].GetKey() != legacy.get(key).GetKey() {
+			t.Fatalf("key %v got %v, want %v", key, results[0].GetKey(), legacy.get(key).GetKey())
+		}
+	}
+	// 删除节点后下标被新节点复用
+	obj.RemoveNode(nodes[10])
+	obj.AddNode(models.NewNormalHashNode("node_new", 1, true))
+	if len(obj.nodeList) != len(nodes) || len(obj.freeIndexes) != 0 {
+		t.Fatalf("node index not reused: %v nodes, %v free", len(obj.nodeList), len(obj.freeIndexes))
+	}
+	for _, key := range benchmarkKeys(10000) {
+		results, _ := obj.Get(key, 1)
+		if results[0].GetKey() == nodes[10].GetKey() {
+			t.Fatalf("key %v got removed node", key)
+		}
+	}
+}
+
+func BenchmarkRingHash_Get(b *testing.B) {
+	obj := NewRingHash(160, 1, benchmarkNodes(2000), utils.GetHashCode)
+	keys := benchmarkKeys(1 << 16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		obj.Get(keys[i&(len(keys)-1)], 1)
+	}
+}
+
+func BenchmarkLegacyRing_Get(b *testing.B) {
+	obj := newLegacyRing(160, benchmarkNodes(2000))
+	keys := benchmarkKeys(1 << 16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		obj.get(keys[i&(len(keys)-1)])
+	}
+}
+
+// BenchmarkRingHash_Build 一次性建环的耗时
+func BenchmarkRingHash_Build(b *testing.B) {
+	nodes := benchmarkNodes(2000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewRingHash(160, 1, nodes, utils.GetHashCode)
+	}
+}
+
+func BenchmarkLegacyRing_Build(b *testing.B) {
+	nodes := benchmarkNodes(2000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newLegacyRing(160, nodes)
+	}
+}
+
+// BenchmarkRingHash_Memory 每个虚拟节点占用的内存
+func BenchmarkRingHash_Memory(b *testing.B) {
+	nodes := benchmarkNodes(2000)
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		obj := NewRingHash(160, 1, nodes, utils.GetHashCode)
+		after := heapInUse()
+		b.ReportMetric(float64(int64(after)-int64(before))/float64(obj.GetSortedKeyCount()), "bytes/vnode")
+		runtime.KeepAlive(obj)
+	}
+}
+
+func BenchmarkLegacyRing_Memory(b *testing.B) {
+	nodes := benchmarkNodes(2000)
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		obj := newLegacyRing(160, nodes)
+		after := heapInUse()
+		b.ReportMetric(float64(int64(after)-int64(before))/float64(len(obj.vnodeSortedList)), "bytes/vnode")
+		runtime.KeepAlive(obj)
+	}
+}
@@ -0,0 +1,112 @@
+package ring_hash
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// HashRange 环上的哈希区间 [Start, End)，End 为 0 表示到 64 位环的末尾。
+// 跨越环零点的区间会被拆分为两个区间
+type HashRange struct {
+	Start uint64
+	End   uint64
+}
+
+// Contains 判断哈希值是否落在区间内
+func (h HashRange) Contains(hash uint64) bool {
+	return hash >= h.Start && (h.End == 0 || hash < h.End)
+}
+
+// ringEnd 环的末尾，ketama 模式为 2^32，64 位环溢出为 0
+func (r *RingHash[T]) ringEnd() uint64 {
+	if r.ketama {
+		return 1 << 32
+	}
+	return 0
+}
+
+// ringSize 环的大小
+func (r *RingHash[T]) ringSize() float64 {
+	if r.ketama {
+		return 1 << 32
+	}
+	return math.Exp2(64)
+}
+
+// OwnedRanges 返回节点负责的哈希区间及其占环的比例。
+// 虚拟节点 p 负责 (前一个虚拟节点, p] 之间的哈希值，即 [前一个虚拟节点+1, p+1)。
+// 未启用的节点仍会返回其区间，查询时这些区间由顺时针方向的下一个节点接管
+func (r *RingHash[T]) OwnedRanges(nodeKey string) ([]HashRange, float64, error) {
+	owner, ok := r.nodeIndexMap[nodeKey]
+	if !ok {
+		return nil, 0, fmt.Errorf("node %v not found", nodeKey)
+	}
+	vnodeCount := len(r.vnodeHashes)
+	if vnodeCount <= 0 {
+		return nil, 0, fmt.Errorf("ring is empty")
+	}
+	ringEnd := r.ringEnd()
+	ranges := make([]HashRange, 0)
+	for i, hashKey := range r.vnodeHashes {
+		// 哈希冲突时只有排在最前的虚拟节点负责区间
+		if r.vnodeOwners[i] != owner || (i > 0 && r.vnodeHashes[i-1] == hashKey) {
+			continue
+		}
+		if i > 0 {
+			ranges = append(ranges, HashRange{Start: r.vnodeHashes[i-1] + 1, End: hashKey + 1})
+			continue
+		}
+		// 第一个虚拟节点负责跨越零点的区间
+		last := r.vnodeHashes[vnodeCount-1]
+		if last+1 != ringEnd {
+			ranges = append(ranges, HashRange{Start: last + 1, End: ringEnd})
+		}
+		ranges = append(ranges, HashRange{Start: 0, End: hashKey + 1})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+	// 合并相邻的区间
+	merged := make([]HashRange, 0, len(ranges))
+	for _, hr := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].End == hr.Start {
+			merged[n-1].End = hr.End
+			continue
+		}
+		merged = append(merged, hr)
+	}
+	fraction := 0.0
+	for _, hr := range merged {
+		fraction += float64(hr.End-hr.Start) / r.ringSize()
+	}
+	return merged, fraction, nil
+}
+
+// Successor 返回大于等于hash的第一个虚拟节点及其所属节点，到达末尾后回到环的起点
+func (r *RingHash[T]) Successor(hash uint64) (uint64, T, error) {
+	var zero T
+	vnodeCount := len(r.vnodeHashes)
+	if vnodeCount <= 0 {
+		return 0, zero, fmt.Errorf("ring is empty")
+	}
+	idx := r.search(hash)
+	if idx == vnodeCount {
+		idx = 0
+	}
+	return r.vnodeHashes[idx], r.nodeList[r.vnodeOwners[idx]], nil
+}
+
+// Predecessor 返回小于hash的最后一个虚拟节点及其所属节点，到达起点后回到环的末尾
+func (r *RingHash[T]) Predecessor(hash uint64) (uint64, T, error) {
+	var zero T
+	vnodeCount := len(r.vnodeHashes)
+	if vnodeCount <= 0 {
+		return 0, zero, fmt.Errorf("ring is empty")
+	}
+	idx := r.search(hash)
+	if idx == 0 {
+		idx = vnodeCount
+	}
+	return r.vnodeHashes[idx-1], r.nodeList[r.vnodeOwners[idx-1]], nil
+}
@@ -1,150 +1,468 @@
 package ring_hash
 
 import (
+	"cmp"
 	"consistent-hash/models"
+	"consistent-hash/utils"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 )
 
+// 环的存储布局
+//  1. 虚拟节点按哈希排序后存放在两个平行的数组中: vnodeHashes 存哈希，vnodeOwners 存所属节点在 nodeList 中的下标。
+//     二分查找只访问紧凑的哈希数组，命中后通过下标取节点，无需查询 map，每个虚拟节点占用 12 字节
+//  2. 节点删除后其下标放入空闲列表，供后续加入的节点复用，nodeList 不会无限增长
 type RingHash[T models.HashNode] struct {
-	vnodeBaseNum    int                 // 虚拟节点基数
-	ringFloorLimit  int                 // 建环的下限
-	totalNodes      int                 // 真实节点数
-	nodeMap         map[string]T        // 节点信息, key为nodeKey
-	vnodeSortedList []uint64            // 已排序的虚拟节点哈希数组
-	vnodeHashMap    map[uint64]T        // 从虚拟节点哈希到真实节点的映射，key为hashCode
-	hashFunc        func([]byte) uint64 // 哈希函数
+	vnodeBaseNum        int                 // 虚拟节点基数
+	ringFloorLimit      int                 // 建环的下限
+	nodeMap             map[string]T        // 节点信息, key为nodeKey
+	nodeVnodeNum        map[string]int      // 节点在环上的虚拟节点数, key为nodeKey
+	nodeList            []T                 // 节点下标表，虚拟节点通过下标引用节点
+	nodeIndexMap        map[string]uint32   // 节点在 nodeList 中的下标, key为nodeKey
+	freeIndexes         []uint32            // nodeList 中空闲的下标
+	vnodeHashes         []uint64            // 已排序的虚拟节点哈希数组
+	vnodeOwners         []uint32            // 虚拟节点所属节点的下标，与 vnodeHashes 一一对应
+	hashFunc            func([]byte) uint64 // 哈希函数
+	ketama              bool                // 是否按 ketama 规则生成虚拟节点
+	loadFactor          float64             // 有界负载系数，为0时不限制节点负载
+	failureDomainLabels []string            // 故障域标签，从大到小排列，如 zone、rack
+	loadLock            sync.Mutex          // 保护节点负载，以及节点变更时对节点表与环的修改
+	loadMap             map[string]int64    // 节点当前负载，key为nodeKey
+	totalLoad           int64               // 所有节点的负载之和
+	nodeEnabled         []bool              // 节点最近一次加入或更新时的启用状态，与 nodeList 一一对应
+	enabledCount        int                 // nodeEnabled 中启用的节点数
+}
+
+// vnode 环上的一个虚拟节点
+type vnode struct {
+	hash  uint64
+	owner uint32
 }
 
 func NewRingHash[T models.HashNode](vnodeBaseNum, ringFloorLimit int, nodes []T,
 	hashFunc func([]byte) uint64) *RingHash[T] {
 	obj := &RingHash[T]{
-		vnodeBaseNum:    vnodeBaseNum,
-		ringFloorLimit:  ringFloorLimit,
-		totalNodes:      len(nodes),
-		nodeMap:         make(map[string]T),
-		vnodeSortedList: make([]uint64, 0),
-		vnodeHashMap:    make(map[uint64]T),
-		hashFunc:        hashFunc,
-	}
-	obj.buildRingHash(nodes)
+		vnodeBaseNum:   vnodeBaseNum,
+		ringFloorLimit: ringFloorLimit,
+		nodeMap:        make(map[string]T),
+		nodeVnodeNum:   make(map[string]int),
+		nodeIndexMap:   make(map[string]uint32),
+		hashFunc:       hashFunc,
+		loadMap:        make(map[string]int64),
+	}
+	obj.AddNodes(nodes)
 	return obj
 }
 
-func (r *RingHash[T]) buildRingHash(nodeList []T) {
-	for _, node := range nodeList {
-		r.nodeMap[node.GetKey()] = node
+// generateVNodeHashKeys 生成节点编号为[from, to)的虚拟节点哈希
+func (r *RingHash[T]) generateVNodeHashKeys(nodeKey string, from, to int) []uint64 {
+	if r.ketama {
+		return generateKetamaPoints(nodeKey, from, to)
 	}
-	// 如果节点数较少，无需建环
-	if len(r.nodeMap) <= r.ringFloorLimit {
+	vnodeHashKeys := make([]uint64, 0, max(to-from, 0))
+	for i := from; i < to; i++ {
+		vnodeKey := []byte(nodeKey + "_" + strconv.Itoa(i))
+		hashCode := r.hashFunc(vnodeKey)
+		vnodeHashKeys = append(vnodeHashKeys, hashCode)
+	}
+	return vnodeHashKeys
+}
+
+// allocIndex 为新节点分配 nodeList 中的下标，优先复用空闲下标
+func (r *RingHash[T]) allocIndex(node T) {
+	var idx uint32
+	if n := len(r.freeIndexes); n > 0 {
+		idx = r.freeIndexes[n-1]
+		r.freeIndexes = r.freeIndexes[:n-1]
+		r.nodeList[idx] = node
+	} else {
+		idx = uint32(len(r.nodeList))
+		r.nodeList = append(r.nodeList, node)
+		r.nodeEnabled = append(r.nodeEnabled, false)
+	}
+	r.nodeIndexMap[node.GetKey()] = idx
+	r.setEnabled(idx, node.IsEnabled())
+}
+
+// freeIndex 释放节点的下标
+func (r *RingHash[T]) freeIndex(nodeKey string) {
+	idx, ok := r.nodeIndexMap[nodeKey]
+	if !ok {
+		return
+	}
+	var zero T
+	r.nodeList[idx] = zero
+	r.setEnabled(idx, false)
+	r.freeIndexes = append(r.freeIndexes, idx)
+	delete(r.nodeIndexMap, nodeKey)
+}
+
+// setEnabled 记录下标为idx的节点的启用状态，维护启用节点数
+func (r *RingHash[T]) setEnabled(idx uint32, enabled bool) {
+	if r.nodeEnabled[idx] == enabled {
+		return
+	}
+	r.nodeEnabled[idx] = enabled
+	if enabled {
+		r.enabledCount++
+	} else {
+		r.enabledCount--
+	}
+}
+
+// insertVNodes 将节点的虚拟节点归并到已排序的数组中，只处理新增的哈希点
+func (r *RingHash[T]) insertVNodes(nodes []T) {
+	added := make([]vnode, 0)
+	for _, node := range nodes {
+		nodeKey := node.GetKey()
+		vnodeCount := max(node.GetWeight()*r.vnodeBaseNum, 0)
+		added = r.addVNodeHashKeys(nodeKey, 0, vnodeCount, added)
+		r.nodeVnodeNum[nodeKey] = vnodeCount
+	}
+	r.mergeVNodes(added)
+}
+
+// addVNodeHashKeys 生成节点编号为[from, to)的虚拟节点，追加到added中
+func (r *RingHash[T]) addVNodeHashKeys(nodeKey string, from, to int, added []vnode) []vnode {
+	owner := r.nodeIndexMap[nodeKey]
+	for _, hashKey := range r.generateVNodeHashKeys(nodeKey, from, to) {
+		added = append(added, vnode{hash: hashKey, owner: owner})
+	}
+	return added
+}
+
+// mergeVNodes 将新增的虚拟节点归并到已排序的数组中。
+// 哈希冲突的虚拟节点都保留在环上，按所属节点的nodeKey排列，查询时nodeKey小的节点优先。
+// 删除其中一个节点后其他节点的虚拟节点仍在原位，环的状态与节点的变更顺序无关
+func (r *RingHash[T]) mergeVNodes(added []vnode) {
+	if len(added) <= 0 {
 		return
 	}
-	for _, node := range nodeList {
-		vnodeHashKeys := r.generateVNodeHashKeys(node)
-		for _, hashKey := range vnodeHashKeys {
-			r.vnodeHashMap[hashKey] = node
+	// 排序规则是全序，一次不稳定排序即可得到确定的结果
+	slices.SortFunc(added, r.compareVNode)
+	hashes := make([]uint64, 0, len(r.vnodeHashes)+len(added))
+	owners := make([]uint32, 0, len(r.vnodeHashes)+len(added))
+	i, j := 0, 0
+	for i < len(r.vnodeHashes) || j < len(added) {
+		if j >= len(added) || (i < len(r.vnodeHashes) &&
+			r.compareVNode(vnode{hash: r.vnodeHashes[i], owner: r.vnodeOwners[i]}, added[j]) <= 0) {
+			hashes = append(hashes, r.vnodeHashes[i])
+			owners = append(owners, r.vnodeOwners[i])
+			i++
+			continue
 		}
+		hashes = append(hashes, added[j].hash)
+		owners = append(owners, added[j].owner)
+		j++
 	}
-	r.updateSortedHashKeys()
+	r.vnodeHashes = hashes
+	r.vnodeOwners = owners
 }
 
-func (r *RingHash[T]) updateSortedHashKeys() {
-	r.vnodeSortedList = make([]uint64, 0, len(r.vnodeHashMap))
-	for hashKey := range r.vnodeHashMap {
-		r.vnodeSortedList = append(r.vnodeSortedList, hashKey)
+// compareVNode 虚拟节点在环上的顺序，哈希相同时按所属节点的nodeKey排列
+func (r *RingHash[T]) compareVNode(a, b vnode) int {
+	if c := cmp.Compare(a.hash, b.hash); c != 0 {
+		return c
 	}
-	sort.Slice(r.vnodeSortedList, func(i, j int) bool {
-		return r.vnodeSortedList[i] < r.vnodeSortedList[j]
+	return strings.Compare(r.nodeList[a.owner].GetKey(), r.nodeList[b.owner].GetKey())
+}
+
+// deleteVNodes 从已排序的数组中删除节点的所有虚拟节点，一次遍历完成
+func (r *RingHash[T]) deleteVNodes(nodeKeys []string) {
+	owners := make(map[uint32]struct{}, len(nodeKeys))
+	for _, nodeKey := range nodeKeys {
+		owners[r.nodeIndexMap[nodeKey]] = struct{}{}
+		delete(r.nodeVnodeNum, nodeKey)
+	}
+	r.filterVNodes(func(_ uint64, owner uint32) bool {
+		_, ok := owners[owner]
+		return ok
 	})
 }
 
-func (r *RingHash[T]) generateVNodeHashKeys(node T) []uint64 {
-	vnodeHashKeys := make([]uint64, 0)
-	vnodeCount := node.GetWeight() * r.vnodeBaseNum
-	for i := 0; i < vnodeCount; i++ {
-		vnodeKey := []byte(node.GetKey() + "_" + strconv.Itoa(i))
-		hashCode := r.hashFunc(vnodeKey)
-		vnodeHashKeys = append(vnodeHashKeys, hashCode)
+// removeVNodeHashKeys 记录节点编号为[from, to)的虚拟节点，value为待删除的个数
+func (r *RingHash[T]) removeVNodeHashKeys(nodeKey string, from, to int, deleted map[vnode]int) {
+	owner := r.nodeIndexMap[nodeKey]
+	for _, hashKey := range r.generateVNodeHashKeys(nodeKey, from, to) {
+		deleted[vnode{hash: hashKey, owner: owner}]++
 	}
-	return vnodeHashKeys
 }
 
-func (r *RingHash[T]) AddNode(node T) {
-	nodeKey := node.GetKey()
-	if _, ok := r.nodeMap[nodeKey]; ok {
+// deleteVNodeHashKeys 从已排序的数组中删除记录的虚拟节点，哈希冲突时只删除所属节点相同的虚拟节点
+func (r *RingHash[T]) deleteVNodeHashKeys(deleted map[vnode]int) {
+	if len(deleted) <= 0 {
 		return
 	}
-	r.nodeMap[nodeKey] = node
+	r.filterVNodes(func(hash uint64, owner uint32) bool {
+		key := vnode{hash: hash, owner: owner}
+		if deleted[key] <= 0 {
+			return false
+		}
+		deleted[key]--
+		return true
+	})
+}
+
+// filterVNodes 从已排序的数组中过滤drop返回true的虚拟节点
+func (r *RingHash[T]) filterVNodes(drop func(hash uint64, owner uint32) bool) {
+	n := 0
+	for i, hashKey := range r.vnodeHashes {
+		if drop(hashKey, r.vnodeOwners[i]) {
+			continue
+		}
+		r.vnodeHashes[n] = hashKey
+		r.vnodeOwners[n] = r.vnodeOwners[i]
+		n++
+	}
+	r.vnodeHashes = r.vnodeHashes[:n]
+	r.vnodeOwners = r.vnodeOwners[:n]
+}
+
+// clearRing 节点数不超过建环下限时清空环
+func (r *RingHash[T]) clearRing() {
+	r.nodeVnodeNum = make(map[string]int)
+	r.vnodeHashes = nil
+	r.vnodeOwners = nil
+}
+
+func (r *RingHash[T]) AddNode(node T) {
+	r.AddNodes([]T{node})
+}
+
+// AddNodes 批量添加节点，已存在的节点被忽略
+func (r *RingHash[T]) AddNodes(nodes []T) {
+	added := make([]T, 0, len(nodes))
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	for _, node := range nodes {
+		nodeKey := node.GetKey()
+		if _, ok := r.nodeMap[nodeKey]; ok {
+			continue
+		}
+		r.nodeMap[nodeKey] = node
+		r.allocIndex(node)
+		added = append(added, node)
+	}
 	// 如果节点数较少，无需建环
-	if len(r.nodeMap) <= r.ringFloorLimit {
+	if len(added) <= 0 || len(r.nodeMap) <= r.ringFloorLimit {
+		return
+	}
+	if r.ketama {
+		r.rebalanceKetama()
 		return
 	}
-	// 添加虚拟节点
-	vnodeHashKeys := r.generateVNodeHashKeys(node)
-	for _, hashKey := range vnodeHashKeys {
-		r.vnodeHashMap[hashKey] = node
+	// 节点数首次超过建环下限时，为所有节点建环
+	if len(r.nodeVnodeNum) <= 0 {
+		added = added[:0]
+		for _, node := range r.nodeMap {
+			added = append(added, node)
+		}
 	}
-	r.updateSortedHashKeys()
+	r.insertVNodes(added)
 }
 
 func (r *RingHash[T]) RemoveNode(node T) {
+	r.RemoveNodes([]T{node})
+}
+
+// RemoveNodes 批量删除节点，不存在的节点被忽略
+func (r *RingHash[T]) RemoveNodes(nodes []T) {
+	removed := make([]string, 0, len(nodes))
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
+	for _, node := range nodes {
+		nodeKey := node.GetKey()
+		if _, ok := r.nodeMap[nodeKey]; !ok {
+			continue
+		}
+		delete(r.nodeMap, nodeKey)
+		r.clearLoad(nodeKey)
+		removed = append(removed, nodeKey)
+	}
+	if len(removed) <= 0 {
+		return
+	}
+	// 节点数不超过建环下限时，无需保留环
+	if len(r.nodeMap) <= r.ringFloorLimit {
+		r.clearRing()
+	} else {
+		r.deleteVNodes(removed)
+		if r.ketama {
+			r.rebalanceKetama()
+		}
+	}
+	for _, nodeKey := range removed {
+		r.freeIndex(nodeKey)
+	}
+}
+
+// UpdateNode 更新节点实例，权重变化时只增删差值部分的虚拟节点。
+// 节点的启用状态改变后也应调用，以更新有界负载的容量
+func (r *RingHash[T]) UpdateNode(node T) {
 	nodeKey := node.GetKey()
+	r.loadLock.Lock()
+	defer r.loadLock.Unlock()
 	if _, ok := r.nodeMap[nodeKey]; !ok {
 		return
 	}
-	delete(r.nodeMap, nodeKey)
-	// 删除虚拟节点
-	vnodeHashKeys := r.generateVNodeHashKeys(node)
-	for _, hashKey := range vnodeHashKeys {
-		delete(r.vnodeHashMap, hashKey)
+	// 已有的虚拟节点通过下标引用节点，只需替换下标表中的实例
+	idx := r.nodeIndexMap[nodeKey]
+	r.nodeMap[nodeKey] = node
+	r.nodeList[idx] = node
+	r.setEnabled(idx, node.IsEnabled())
+	// 未建环时只需更新节点实例
+	if len(r.nodeMap) <= r.ringFloorLimit {
+		return
+	}
+	if r.ketama {
+		r.rebalanceKetama()
+		return
+	}
+	deleted := make(map[vnode]int)
+	added := r.resizeVNodes(nodeKey, max(node.GetWeight()*r.vnodeBaseNum, 0), make([]vnode, 0), deleted)
+	r.deleteVNodeHashKeys(deleted)
+	r.mergeVNodes(added)
+}
+
+// resizeVNodes 将节点的虚拟节点数调整为newCount，新增与删除的哈希点分别记录在added与deleted中
+func (r *RingHash[T]) resizeVNodes(nodeKey string, newCount int, added []vnode, deleted map[vnode]int) []vnode {
+	oldCount := r.nodeVnodeNum[nodeKey]
+	if newCount > oldCount {
+		added = r.addVNodeHashKeys(nodeKey, oldCount, newCount, added)
+	} else if newCount < oldCount {
+		r.removeVNodeHashKeys(nodeKey, newCount, oldCount, deleted)
 	}
+	r.nodeVnodeNum[nodeKey] = newCount
+	return added
 }
 
+// Get 获取key对应的至多number个节点，跳过未启用的节点。
+// 未启用的节点仍保留在环上，重新启用时无需重建环。
+// 设置了故障域标签时，优先选取故障域未被使用的节点
 func (r *RingHash[T]) Get(key string, number int) ([]T, error) {
 	// 期望获取的节点数量和真实节点数量做比较
-	if number > r.totalNodes {
-		number = r.totalNodes
+	if number > len(r.nodeMap) {
+		number = len(r.nodeMap)
 	}
-	// 无需从环上取
+	hashCode := r.hashFunc(utils.StringToBytes(key))
+	// 无需从环上取，按加权 rendezvous 得分排序，保证同一个key在任何进程中得到相同的节点
 	if len(r.nodeMap) <= r.ringFloorLimit {
-		if number > len(r.nodeMap) {
-			number = len(r.nodeMap)
-		}
-		results := make([]T, 0, number)
-		for _, node := range r.nodeMap {
-			results = append(results, node)
-		}
-		return results, nil
+		candidates := r.rendezvousOrder(hashCode)
+		return r.collect(func(visit func(node T) bool) {
+			for _, node := range candidates {
+				if !visit(node) {
+					return
+				}
+			}
+		}, number), nil
 	}
 	// 判断环是否为空
-	if len(r.vnodeSortedList) <= 0 {
+	if len(r.vnodeHashes) <= 0 {
 		return []T{}, fmt.Errorf("ring is empty")
 	}
-	hashCode := r.hashFunc([]byte(key))
-	// 二分法找到第一个大于等于 hashCode 的索引。如果都小于，则返回 0(环形)
-	idx := sort.Search(len(r.vnodeSortedList), func(i int) bool {
-		return r.vnodeSortedList[i] >= hashCode
-	})
-	if idx == len(r.vnodeSortedList) {
-		idx = 0
+	// 只取一个节点时直接查找，避免去重的开销
+	if number == 1 {
+		if node, ok := r.first(hashCode); ok {
+			return []T{node}, nil
+		}
+		return []T{}, nil
 	}
 	// 顺时针收集不重复的节点
-	results := make([]T, 0, number)
-	seen := make(map[string]struct{})
-	vnodeCount := len(r.vnodeSortedList)
-	for i := 0; len(results) < number; i++ {
-		vnodeHashCode := r.vnodeSortedList[(idx+i)%vnodeCount]
-		node := r.vnodeHashMap[vnodeHashCode]
-		keyStr := node.GetKey()
-		if _, ok := seen[keyStr]; !ok {
-			seen[keyStr] = struct{}{}
-			results = append(results, node)
+	return r.collect(func(visit func(node T) bool) {
+		r.walk(hashCode, visit)
+	}, number), nil
+}
+
+// search 二分查找第一个大于等于hashCode的虚拟节点下标，都小于时返回虚拟节点数
+func (r *RingHash[T]) search(hashCode uint64) int {
+	lo, hi := 0, len(r.vnodeHashes)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if r.vnodeHashes[mid] < hashCode {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// walk 从hashCode开始顺时针遍历虚拟节点，visit返回false时停止，最多遍历一圈
+func (r *RingHash[T]) walk(hashCode uint64, visit func(node T) bool) {
+	vnodeCount := len(r.vnodeHashes)
+	if vnodeCount <= 0 {
+		return
+	}
+	// 找到第一个大于等于 hashCode 的索引。如果都小于，则返回 0(环形)
+	idx := r.search(hashCode)
+	if idx == vnodeCount {
+		idx = 0
+	}
+	for i := 0; i < vnodeCount; i++ {
+		if !visit(r.nodeList[r.vnodeOwners[idx]]) {
+			return
+		}
+		if idx++; idx == vnodeCount {
+			idx = 0
+		}
+	}
+}
+
+// rendezvousOrder 未建环时按加权 rendezvous 得分从高到低排列节点，得分相同时按nodeKey排序
+func (r *RingHash[T]) rendezvousOrder(hashCode uint64) []T {
+	nodes := make([]T, 0, len(r.nodeMap))
+	scores := make(map[string]float64, len(r.nodeMap))
+	for nodeKey, node := range r.nodeMap {
+		nodeHash := utils.Mix64(hashCode ^ r.hashFunc([]byte(nodeKey)))
+		scores[nodeKey] = utils.WeightedHashScore(nodeHash, node.GetWeight())
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		si, sj := scores[nodes[i].GetKey()], scores[nodes[j].GetKey()]
+		if si != sj {
+			return si > sj
+		}
+		return nodes[i].GetKey() < nodes[j].GetKey()
+	})
+	return nodes
+}
+
+// first 从hashCode开始顺时针查找第一个启用的节点
+func (r *RingHash[T]) first(hashCode uint64) (T, bool) {
+	vnodeCount := len(r.vnodeHashes)
+	idx := r.search(hashCode)
+	for i := 0; i < vnodeCount; i++ {
+		if idx == vnodeCount {
+			idx = 0
 		}
+		if node := r.nodeList[r.vnodeOwners[idx]]; node.IsEnabled() {
+			return node, true
+		}
+		idx++
 	}
-	return results, nil
+	var zero T
+	return zero, false
 }
 
 func (r *RingHash[T]) GetSortedKeyCount() int {
-	return len(r.vnodeSortedList)
+	return len(r.vnodeHashes)
+}
+
+func (r *RingHash[T]) GetNodes() []T {
+	nodes := make([]T, 0, len(r.nodeMap))
+	for _, node := range r.nodeMap {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].GetKey() < nodes[j].GetKey()
+	})
+	return nodes
+}
+
+func (r *RingHash[T]) GetNodeCount() int {
+	return len(r.nodeMap)
 }
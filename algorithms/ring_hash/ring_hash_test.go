@@ -3,7 +3,9 @@ package ring_hash
 import (
 	"consistent-hash/models"
 	"consistent-hash/utils"
+	"math"
 	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -159,3 +161,448 @@ func TestRingHash_UpdateNode(t *testing.T) {
 	}
 	t.Logf("adjust num: %v\n", adjustNum3)
 }
+
+func TestRingHash_BoundedLoad(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewRingHash(160, 1, nodes, utils.GetHashCode)
+	loadFactor := 1.25
+	if err := obj.SetLoadFactor(loadFactor); err != nil {
+		t.Fatalf("set load factor err: %v", err)
+	}
+	keyCount := 10000
+	for i := 0; i < keyCount; i++ {
+		node, err := obj.GetBounded("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("get bounded err: %v", err)
+		}
+		obj.Acquire(node.GetKey())
+	}
+	maxLoad := int64(math.Ceil(loadFactor * float64(keyCount) / float64(len(nodes))))
+	for nodeKey, load := range obj.GetLoads() {
+		if load > maxLoad {
+			t.Errorf("node %v load %v exceeds capacity %v", nodeKey, load, maxLoad)
+		}
+	}
+
+	// 释放负载后，key回到原节点
+	for nodeKey, load := range obj.GetLoads() {
+		for i := int64(0); i < load; i++ {
+			obj.Release(nodeKey)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		node, err := obj.GetBounded(key)
+		if err != nil {
+			t.Fatalf("get bounded err: %v", err)
+		}
+		results, _ := obj.Get(key, 1)
+		if node.GetKey() != results[0].GetKey() {
+			t.Fatalf("key %v bounded node %v, want %v", key, node.GetKey(), results[0].GetKey())
+		}
+	}
+}
+
+func TestRingHash_BoundedLoadConcurrent(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewRingHash(160, 1, nodes, utils.GetHashCode)
+	loadFactor := 1.25
+	if err := obj.SetLoadFactor(loadFactor); err != nil {
+		t.Fatalf("set load factor err: %v", err)
+	}
+	// 所有请求都落在同一个位置，并发分配时检查与增加负载必须是原子的
+	workers, keyCount := 8, 1000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < keyCount; i++ {
+				if _, err := obj.GetBoundedAndAcquire("hot_key"); err != nil {
+					t.Errorf("get bounded err: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// 增加负载与删除节点并发，被删除节点的负载不会残留
+	expectedTotal := int64(workers*keyCount) - obj.GetLoad(nodes[9].GetKey())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < keyCount; i++ {
+			obj.Acquire(nodes[9].GetKey())
+		}
+	}()
+	obj.RemoveNode(nodes[9])
+	wg.Wait()
+	loads := obj.GetLoads()
+	total := int64(0)
+	maxLoad := int64(math.Ceil(loadFactor * float64(workers*keyCount) / float64(len(nodes)-1)))
+	for nodeKey, load := range loads {
+		if nodeKey == nodes[9].GetKey() && load > 0 {
+			t.Fatalf("removed node %v has load %v", nodeKey, load)
+		}
+		if load > maxLoad {
+			t.Fatalf("node %v load %v exceeds capacity %v", nodeKey, load, maxLoad)
+		}
+		total += load
+	}
+	if total != expectedTotal {
+		t.Fatalf("total load %v, want %v", total, expectedTotal)
+	}
+
+	// 有界查询与节点增删并发，查询只会返回环上现有的节点
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < keyCount; i++ {
+			node, err := obj.GetBoundedAndAcquire("key_" + strconv.Itoa(i))
+			if err != nil {
+				t.Errorf("get bounded err: %v", err)
+				return
+			}
+			obj.Release(node.GetKey())
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		obj.AddNodes(nodes[9:])
+		obj.RemoveNodes(nodes[8:])
+		obj.AddNode(nodes[8])
+	}
+	wg.Wait()
+	if obj.GetNodeCount() != 9 {
+		t.Fatalf("node count %v, want 9", obj.GetNodeCount())
+	}
+}
+
+func TestRingHash_BoundedLoadDisabledNode(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 4)
+	for i := 0; i < 4; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	obj := NewRingHash(160, 1, nodes, utils.GetHashCode)
+	if err := obj.SetLoadFactor(1); err != nil {
+		t.Fatalf("set load factor err: %v", err)
+	}
+	// 禁用节点后调用 UpdateNode，容量按启用的3个节点计算
+	nodes[3].SetEnabled(false)
+	obj.UpdateNode(nodes[3])
+	for i := 0; i < 300; i++ {
+		node, err := obj.GetBoundedAndAcquire("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("get bounded err: %v", err)
+		}
+		if node.GetKey() == nodes[3].GetKey() {
+			t.Fatalf("key_%v got disabled node", i)
+		}
+	}
+	for _, node := range nodes[:3] {
+		if load := obj.GetLoad(node.GetKey()); load != 100 {
+			t.Fatalf("node %v load %v, want 100", node.GetKey(), load)
+		}
+	}
+	// 重新启用后容量按4个节点计算
+	nodes[3].SetEnabled(true)
+	obj.UpdateNode(nodes[3])
+	if node, err := obj.GetBoundedAndAcquire("key_0"); err != nil || node.GetKey() != nodes[3].GetKey() {
+		t.Fatalf("get bounded got %v, %v, want %v", node, err, nodes[3].GetKey())
+	}
+}
+
+func TestRingHash_BatchUpdate(t *testing.T) {
+	nodes := make([]models.HashNode, 0, 110)
+	for i := 0; i < 110; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true))
+	}
+	// 批量添加与一次性建环的结果一致
+	obj := NewRingHash(100, 1, nodes[:10], utils.GetHashCode)
+	obj.AddNodes(nodes[10:])
+	expected := NewRingHash(100, 1, nodes, utils.GetHashCode)
+	if obj.GetSortedKeyCount() != expected.GetSortedKeyCount() {
+		t.Fatalf("sorted key count %v, want %v", obj.GetSortedKeyCount(), expected.GetSortedKeyCount())
+	}
+	for i := 0; i < 10000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, _ := obj.Get(key, 1)
+		expectedResults, _ := expected.Get(key, 1)
+		if results[0].GetKey() != expectedResults[0].GetKey() {
+			t.Fatalf("key %v node %v, want %v", key, results[0].GetKey(), expectedResults[0].GetKey())
+		}
+	}
+
+	// 批量删除后环上不再有被删除的节点
+	obj.RemoveNodes(nodes[10:])
+	if obj.GetNodeCount() != 10 || obj.GetSortedKeyCount() != 10*100 {
+		t.Fatalf("node count %v, sorted key count %v after remove", obj.GetNodeCount(), obj.GetSortedKeyCount())
+	}
+	for i := 0; i < 10000; i++ {
+		results, err := obj.Get("key_"+strconv.Itoa(i), 3)
+		if err != nil {
+			t.Fatalf("ring hash err: %v", err)
+		}
+		for _, node := range results {
+			if idx, _ := strconv.Atoi(node.GetKey()[len("node_"):]); idx >= 10 {
+				t.Fatalf("get removed node %v", node.GetKey())
+			}
+		}
+	}
+}
+
+func TestRingHash_HashCollision(t *testing.T) {
+	// 哈希空间很小，虚拟节点之间大量冲突
+	hashFunc := func(key []byte) uint64 {
+		return utils.GetHashCode(key) % 4096
+	}
+	nodes := make([]models.HashNode, 0, 20)
+	for i := 0; i < 20; i++ {
+		nodes = append(nodes, models.NewNormalHashNode("node_"+strconv.Itoa(i), 1+i%3, true))
+	}
+	obj := NewRingHash(20, 1, nodes[:15], hashFunc)
+	obj.RemoveNodes(nodes[3:6])
+	obj.AddNodes(nodes[15:])
+	obj.UpdateNode(models.NewNormalHashNode("node_1", 3, true))
+	obj.RemoveNode(nodes[8])
+	obj.AddNodes([]models.HashNode{nodes[5], nodes[8], nodes[3], nodes[4]})
+	obj.UpdateNode(models.NewNormalHashNode("node_1", 2, true))
+	// 变更后的环与一次性建环的结果完全一致
+	final := make([]models.HashNode, 0, len(nodes))
+	final = append(final, nodes...)
+	final[1] = models.NewNormalHashNode("node_1", 2, true)
+	expected := NewRingHash(20, 1, final, hashFunc)
+	if obj.GetSortedKeyCount() != expected.GetSortedKeyCount() {
+		t.Fatalf("sorted key count %v, want %v", obj.GetSortedKeyCount(), expected.GetSortedKeyCount())
+	}
+	for i, hashKey := range expected.vnodeHashes {
+		nodeKey := obj.nodeList[obj.vnodeOwners[i]].GetKey()
+		expectedKey := expected.nodeList[expected.vnodeOwners[i]].GetKey()
+		if obj.vnodeHashes[i] != hashKey || nodeKey != expectedKey {
+			t.Fatalf("vnode %v is %v of %v, want %v of %v", i, obj.vnodeHashes[i], nodeKey, hashKey, expectedKey)
+		}
+	}
+	// 冲突的虚拟节点只有一个负责区间，所有节点的区间占满整个环
+	total := 0.0
+	for _, node := range final {
+		_, fraction, err := obj.OwnedRanges(node.GetKey())
+		if err != nil {
+			t.Fatalf("owned ranges err: %v", err)
+		}
+		total += fraction
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Fatalf("owned fraction sum %v, want 1", total)
+	}
+}
+
+func TestRingHash_UpdateNodeWeight(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 3, true),
+		models.NewNormalHashNode("node_2", 6, true),
+		models.NewNormalHashNode("node_3", 1, true),
+	}
+	obj := NewRingHash(100, 1, nodes, utils.GetHashCode)
+	obj.UpdateNode(models.NewNormalHashNode("node_1", 4, true))
+	obj.UpdateNode(models.NewNormalHashNode("node_2", 5, true))
+	expected := NewRingHash(100, 1, []models.HashNode{
+		models.NewNormalHashNode("node_1", 4, true),
+		models.NewNormalHashNode("node_2", 5, true),
+		models.NewNormalHashNode("node_3", 1, true),
+	}, utils.GetHashCode)
+	if obj.GetSortedKeyCount() != expected.GetSortedKeyCount() {
+		t.Fatalf("sorted key count %v, want %v", obj.GetSortedKeyCount(), expected.GetSortedKeyCount())
+	}
+	for i := 0; i < 10000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, _ := obj.Get(key, 1)
+		expectedResults, _ := expected.Get(key, 1)
+		if results[0].GetKey() != expectedResults[0].GetKey() {
+			t.Fatalf("key %v node %v, want %v", key, results[0].GetKey(), expectedResults[0].GetKey())
+		}
+		if results[0].GetWeight() != expectedResults[0].GetWeight() {
+			t.Fatalf("key %v node weight %v, want %v", key, results[0].GetWeight(), expectedResults[0].GetWeight())
+		}
+	}
+}
+
+func TestRingHash_DisabledNode(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 1, true),
+		models.NewNormalHashNode("node_3", 1, true),
+	}
+	obj := NewRingHash(100, 1, nodes, utils.GetHashCode)
+	before := make(map[string]string)
+	for i := 0; i < 10000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, _ := obj.Get(key, 1)
+		before[key] = results[0].GetKey()
+	}
+	nodes[1].SetEnabled(false)
+	for i := 0; i < 10000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, err := obj.Get(key, 3)
+		if err != nil {
+			t.Fatalf("ring hash err: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("key %v got %v nodes, want 2", key, len(results))
+		}
+		if results[0].GetKey() == "node_2" {
+			t.Fatalf("key %v got disabled node", key)
+		}
+		// 只有被禁用节点上的key发生迁移
+		if before[key] != "node_2" && before[key] != results[0].GetKey() {
+			t.Fatalf("key %v moved from %v to %v", key, before[key], results[0].GetKey())
+		}
+	}
+}
+
+func TestRingHash_OwnedRanges(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 2, true),
+		models.NewNormalHashNode("node_3", 3, true),
+	}
+	for _, obj := range []*RingHash[models.HashNode]{
+		NewRingHash(50, 1, nodes, utils.GetHashCode),
+		NewKetamaRingHash(nodes),
+	} {
+		totalFraction := 0.0
+		for _, node := range nodes {
+			ranges, fraction, err := obj.OwnedRanges(node.GetKey())
+			if err != nil {
+				t.Fatalf("owned ranges err: %v", err)
+			}
+			totalFraction += fraction
+			// 区间的端点由该节点负责
+			for _, hr := range ranges {
+				for _, hash := range []uint64{hr.Start, hr.End - 1} {
+					_, owner, err := obj.Successor(hash)
+					if err != nil {
+						t.Fatalf("successor err: %v", err)
+					}
+					if owner.GetKey() != node.GetKey() {
+						t.Fatalf("hash %v in range %+v owned by %v, want %v", hash, hr, owner.GetKey(), node.GetKey())
+					}
+				}
+			}
+		}
+		if math.Abs(totalFraction-1) > 1e-9 {
+			t.Fatalf("total fraction %v, want 1", totalFraction)
+		}
+		// 前驱与后继相邻
+		for i := 0; i < 100; i++ {
+			hash := obj.hashFunc([]byte("key_" + strconv.Itoa(i)))
+			succ, _, _ := obj.Successor(hash)
+			pred, _, _ := obj.Predecessor(hash)
+			nextOfPred, _, _ := obj.Successor(pred + 1)
+			if nextOfPred != succ {
+				t.Fatalf("successor of predecessor %v is %v, want %v", pred, nextOfPred, succ)
+			}
+		}
+	}
+}
+
+func TestRingHash_FailureDomain(t *testing.T) {
+	// 2 个 zone，每个 zone 2 个 rack，每个 rack 3 个节点
+	nodes := make([]*models.NormalHashNode, 0, 12)
+	for i := 0; i < 12; i++ {
+		node := models.NewNormalHashNode("node_"+strconv.Itoa(i), 1, true)
+		node.SetLabel("zone", "zone_"+strconv.Itoa(i/6))
+		node.SetLabel("rack", "rack_"+strconv.Itoa(i/3%2))
+		nodes = append(nodes, node)
+	}
+	plain := NewRingHash(100, 1, nodes, utils.GetHashCode)
+	obj := NewRingHash(100, 1, nodes, utils.GetHashCode)
+	obj.SetFailureDomainLabels("zone", "rack")
+	rackOf := func(node *models.NormalHashNode) string {
+		return node.GetLabel("zone") + "/" + node.GetLabel("rack")
+	}
+	for i := 0; i < 10000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		expected, _ := plain.Get(key, 1)
+		results, err := obj.Get(key, 5)
+		if err != nil {
+			t.Fatalf("ring hash err: %v", err)
+		}
+		if len(results) != 5 {
+			t.Fatalf("key %v got %v nodes, want 5", key, len(results))
+		}
+		// 第一个副本与不设置故障域时一致
+		if results[0].GetKey() != expected[0].GetKey() {
+			t.Fatalf("key %v primary %v, want %v", key, results[0].GetKey(), expected[0].GetKey())
+		}
+		// 前两个副本分布在不同 zone，前四个副本分布在不同 rack
+		if results[0].GetLabel("zone") == results[1].GetLabel("zone") {
+			t.Fatalf("key %v replicas in same zone", key)
+		}
+		racks := make(map[string]struct{})
+		keys := make(map[string]struct{})
+		for j, node := range results {
+			if j < 4 {
+				racks[rackOf(node)] = struct{}{}
+			}
+			keys[node.GetKey()] = struct{}{}
+		}
+		if len(racks) != 4 || len(keys) != 5 {
+			t.Fatalf("key %v replicas not spread: %v racks, %v nodes", key, len(racks), len(keys))
+		}
+	}
+	// 节点较少无需建环时同样分散副本
+	small := NewRingHash(100, 20, nodes, utils.GetHashCode)
+	small.SetFailureDomainLabels("zone")
+	results, _ := small.Get("key", 2)
+	if len(results) != 2 || results[0].GetLabel("zone") == results[1].GetLabel("zone") {
+		t.Fatalf("small cluster replicas not spread: %v", len(results))
+	}
+}
+
+func TestRingHash_SmallCluster(t *testing.T) {
+	nodes := []models.HashNode{
+		models.NewNormalHashNode("node_1", 1, true),
+		models.NewNormalHashNode("node_2", 2, true),
+		models.NewNormalHashNode("node_3", 1, true),
+	}
+	// 节点数不超过建环下限，不同的加入顺序得到相同的结果
+	obj := NewRingHash(100, 10, nodes, utils.GetHashCode)
+	reversed := NewRingHash(100, 10, []models.HashNode{nodes[2], nodes[1], nodes[0]}, utils.GetHashCode)
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 40000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		results, err := obj.Get(key, 3)
+		if err != nil {
+			t.Fatalf("ring hash err: %v", err)
+		}
+		again, _ := reversed.Get(key, 3)
+		for j := range results {
+			if results[j].GetKey() != again[j].GetKey() {
+				t.Fatalf("key %v not deterministic: %v vs %v", key, results[j].GetKey(), again[j].GetKey())
+			}
+		}
+		before[key] = results[0].GetKey()
+		counts[results[0].GetKey()]++
+	}
+	// 节点命中比例与权重成正比
+	for _, node := range nodes {
+		expected := 40000 * node.GetWeight() / 4
+		if math.Abs(float64(counts[node.GetKey()]-expected)) > float64(expected)*0.05 {
+			t.Fatalf("node %v got %v keys, want about %v", node.GetKey(), counts[node.GetKey()], expected)
+		}
+	}
+	// 删除节点后只有该节点上的key发生迁移
+	obj.RemoveNode(nodes[1])
+	for key, nodeKey := range before {
+		results, _ := obj.Get(key, 1)
+		if nodeKey != "node_2" && results[0].GetKey() != nodeKey {
+			t.Fatalf("key %v moved from %v to %v", key, nodeKey, results[0].GetKey())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * ketama 黄金向量生成程序
+ * 按 libmemcached 1.0 libmemcached/hosts.cc update_continuum (ketama weighted 分支)
+ * 与 libhashkit md5.cc hashkit_md5 / ketama_server_hash 逐段移植，MD5 使用 OpenSSL。
+ * 编译: cc -Wno-deprecated-declarations -o ketama_vectors ketama_vectors.c -lcrypto -lm
+ * 用法: ketama_vectors host port weight [host port weight ...] < keys
+ */
+#include <math.h>
+#include <openssl/md5.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define MEMCACHED_DEFAULT_PORT 11211
+#define MEMCACHED_POINTS_PER_SERVER_KETAMA 160
+#define MAX_SERVERS 64
+
+struct server {
+  const char *hostname;
+  unsigned port;
+  uint32_t weight;
+};
+
+struct continuum_item {
+  uint32_t index;
+  uint32_t value;
+};
+
+/* libhashkit ketama_server_hash */
+static uint32_t ketama_server_hash(const char *key, size_t key_length, uint32_t alignment) {
+  unsigned char results[16];
+  MD5((const unsigned char *)key, key_length, results);
+  return ((uint32_t)(results[3 + alignment * 4] & 0xFF) << 24) |
+         ((uint32_t)(results[2 + alignment * 4] & 0xFF) << 16) |
+         ((uint32_t)(results[1 + alignment * 4] & 0xFF) << 8) |
+         (results[0 + alignment * 4] & 0xFF);
+}
+
+/* libhashkit hashkit_md5 */
+static uint32_t hashkit_md5(const char *key, size_t key_length) {
+  return ketama_server_hash(key, key_length, 0);
+}
+
+static int continuum_item_cmp(const void *t1, const void *t2) {
+  const struct continuum_item *ct1 = t1, *ct2 = t2;
+  if (ct1->value == ct2->value) {
+    return 0;
+  } else if (ct1->value > ct2->value) {
+    return 1;
+  }
+  return -1;
+}
+
+int main(int argc, char **argv) {
+  struct server list[MAX_SERVERS];
+  uint32_t live_servers = 0;
+  uint64_t total_weight = 0;
+  for (int i = 1; i + 2 < argc && live_servers < MAX_SERVERS; i += 3) {
+    list[live_servers].hostname = argv[i];
+    list[live_servers].port = (unsigned)atoi(argv[i + 1]);
+    list[live_servers].weight = (uint32_t)atoi(argv[i + 2]);
+    total_weight += list[live_servers].weight;
+    live_servers++;
+  }
+
+  struct continuum_item *continuum =
+      calloc(live_servers * MEMCACHED_POINTS_PER_SERVER_KETAMA * 2, sizeof(struct continuum_item));
+  uint32_t continuum_index = 0;
+  for (uint32_t host_index = 0; host_index < live_servers; ++host_index) {
+    float pct = (float)list[host_index].weight / (float)total_weight;
+    uint32_t pointer_per_server =
+        (uint32_t)((floor((float)(pct * MEMCACHED_POINTS_PER_SERVER_KETAMA / 4 * (float)live_servers + 0.0000000001))) * 4);
+    uint32_t pointer_per_hash = 4;
+    for (uint32_t pointer_index = 1; pointer_index <= pointer_per_server / pointer_per_hash; pointer_index++) {
+      char sort_host[1024];
+      int sort_host_length;
+      if (list[host_index].port == MEMCACHED_DEFAULT_PORT) {
+        sort_host_length = snprintf(sort_host, sizeof(sort_host), "%s-%u", list[host_index].hostname, pointer_index - 1);
+      } else {
+        sort_host_length = snprintf(sort_host, sizeof(sort_host), "%s:%u-%u", list[host_index].hostname,
+                                    list[host_index].port, pointer_index - 1);
+      }
+      for (uint32_t x = 0; x < pointer_per_hash; x++) {
+        uint32_t value = ketama_server_hash(sort_host, (size_t)sort_host_length, x);
+        continuum[continuum_index].index = host_index;
+        continuum[continuum_index++].value = value;
+      }
+    }
+  }
+  qsort(continuum, continuum_index, sizeof(struct continuum_item), continuum_item_cmp);
+  printf("# points %u\n", continuum_index);
+
+  /* libmemcached/hash.cc dispatch_host */
+  char key[1024];
+  while (fgets(key, sizeof(key), stdin)) {
+    key[strcspn(key, "\n")] = 0;
+    uint32_t hash = hashkit_md5(key, strlen(key));
+    struct continuum_item *begin = continuum, *left = continuum, *right = continuum + continuum_index;
+    struct continuum_item *end = right;
+    while (left < right) {
+      struct continuum_item *middle = left + (right - left) / 2;
+      if (middle->value < hash) {
+        left = middle + 1;
+      } else {
+        right = middle;
+      }
+    }
+    if (right == end) {
+      right = begin;
+    }
+    printf("%s\t%s:%u\n", key, list[right->index].hostname, list[right->index].port);
+  }
+  return 0;
+}
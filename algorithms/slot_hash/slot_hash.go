@@ -83,8 +83,12 @@ func (r *SlotHash[T]) Get(key string) []T {
 	return results
 }
 
+// AddNode 添加节点，节点已存在时忽略，否则节点会按配额再占用一遍槽位
 func (r *SlotHash[T]) AddNode(node T) {
 	nodeKey := node.GetKey()
+	if _, ok := r.nodeInstanceMap[nodeKey]; ok {
+		return
+	}
 	r.nodeInstanceMap[nodeKey] = node
 	r.nodeReplicaQuotaMap[nodeKey] = getNodeQuota(node.GetWeight())
 	r.nodeSlotScoreMap[nodeKey] = getNodeSlotScore(r.hashFunc, nodeKey)
@@ -379,3 +383,18 @@ func getHashScore(hashFunc func([]byte) uint64, nodeKey string, slot int) float6
 	logScore := 1.0 / -math.Log(normalizedHash)
 	return logScore
 }
+
+func (r *SlotHash[T]) GetNodes() []T {
+	nodes := make([]T, 0, len(r.nodeInstanceMap))
+	for _, node := range r.nodeInstanceMap {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].GetKey() < nodes[j].GetKey()
+	})
+	return nodes
+}
+
+func (r *SlotHash[T]) GetNodeCount() int {
+	return len(r.nodeInstanceMap)
+}
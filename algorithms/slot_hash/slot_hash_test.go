@@ -4,6 +4,7 @@ import (
 	"consistent-hash/models"
 	"consistent-hash/utils"
 	"fmt"
+	"slices"
 	"testing"
 )
 
@@ -23,3 +24,22 @@ func TestSlotHash(t *testing.T) {
 		t.Logf("slot: %v nodes: %v", slot, nodes)
 	}
 }
+
+func TestSlotHash_AddExistingNode(t *testing.T) {
+	nodeList := make([]*models.NormalHashNode, 0, 10)
+	for i := 0; i < 10; i++ {
+		nodeList = append(nodeList, models.NewNormalHashNode(fmt.Sprintf("node_%d", i), 100, true))
+	}
+	slotHash := NewSlotHash(nodeList, utils.GetHashCode)
+	before := slotHash.GetSlotTable()
+	// 重复添加已存在的节点不改变槽位表
+	slotHash.AddNode(nodeList[3])
+	after := slotHash.GetSlotTable()
+	for slot := range before {
+		slices.Sort(before[slot])
+		slices.Sort(after[slot])
+		if !slices.Equal(before[slot], after[slot]) {
+			t.Fatalf("slot %v nodes %v, want %v", slot, after[slot], before[slot])
+		}
+	}
+}
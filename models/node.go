@@ -8,10 +8,17 @@ type HashNode interface {
 	SetEnabled(isEnabled bool)
 }
 
+// LabeledHashNode 带标签的节点，标签可用于描述机房(zone)、机架(rack)等故障域
+type LabeledHashNode interface {
+	HashNode
+	GetLabel(name string) string
+}
+
 type NormalHashNode struct {
 	key       string
 	weight    int
 	isEnabled bool
+	labels    map[string]string
 }
 
 func NewNormalHashNode(key string, weight int, isEnabled bool) *NormalHashNode {
@@ -42,10 +49,29 @@ func (r *NormalHashNode) SetEnabled(isEnabled bool) {
 	r.isEnabled = isEnabled
 }
 
+func (r *NormalHashNode) GetLabel(name string) string {
+	return r.labels[name]
+}
+
+func (r *NormalHashNode) SetLabel(name, value string) {
+	if r.labels == nil {
+		r.labels = make(map[string]string)
+	}
+	r.labels[name] = value
+}
+
 func (r *NormalHashNode) DeepCopy() *NormalHashNode {
+	var labels map[string]string
+	if r.labels != nil {
+		labels = make(map[string]string, len(r.labels))
+		for name, value := range r.labels {
+			labels[name] = value
+		}
+	}
 	return &NormalHashNode{
 		key:       r.key,
 		weight:    r.weight,
 		isEnabled: r.isEnabled,
+		labels:    labels,
 	}
 }
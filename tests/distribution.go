@@ -33,11 +33,20 @@ func testDistribution() {
 	ringHash40 := ring_hash.NewRingHash(40, 1, nodeList, utils.GetHashCode) // 添加40个虚拟节点的测试
 	ringHash160 := ring_hash.NewRingHash(160, 1, nodeList, utils.GetHashCode)
 	rendezvousHash := rendezvous_hash.NewRendezvousHash(nodeList, utils.GetHashCode)
+	hierarchicalRendezvousHash := rendezvous_hash.NewHierarchicalRendezvousHash(nodeList, 8, utils.GetHashCode)
 	jumpHash := jump_hash.NewJumpHash(nodeList, utils.GetHashCode)
-	maglevHash2039 := maglev_hash.NewMaglevHash(nodeKeyList, 2039)
-	maglevHash65537 := maglev_hash.NewMaglevHash(nodeKeyList, 65537)
+	maglevHash2039, err := maglev_hash.NewMaglevHash(nodeList, 2039, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		fmt.Printf("maglevHash2039 new err: %v\n", err)
+		return
+	}
+	maglevHash65537, err := maglev_hash.NewMaglevHash(nodeList, 65537, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		fmt.Printf("maglevHash65537 new err: %v\n", err)
+		return
+	}
 	anchorHash2000 := anchor_hash.NewAnchorHash(nodeKeyList, 2000, utils.GetHashCode)
-	dxHash := dx_hash.NewDxHash(nodeList, nodeCount)
+	dxHash := dx_hash.NewDxHash(nodeList, nodeCount, nil)
 	slotHash := slot_hash.NewSlotHash(nodeList, utils.GetHashCode)
 
 	// 生成测试键
@@ -79,6 +88,17 @@ func testDistribution() {
 		rendezvousHashDistribution[node.GetKey()]++
 	}
 
+	// 测试层次化Rendezvous哈希分布
+	hierarchicalRendezvousHashDistribution := make(map[string]int)
+	for _, key := range keys {
+		node, err := hierarchicalRendezvousHash.Get(key)
+		if err != nil {
+			fmt.Printf("hierarchicalRendezvousHash get err: %v\n", err)
+			return
+		}
+		hierarchicalRendezvousHashDistribution[node.GetKey()]++
+	}
+
 	// 测试跳跃哈希分布
 	jumpHashDistribution := make(map[string]int)
 	for _, key := range keys {
@@ -98,7 +118,7 @@ func testDistribution() {
 			fmt.Printf("maglevHash2039 get err: %v\n", err)
 			return
 		}
-		maglevHash2039Distribution[node]++
+		maglevHash2039Distribution[node.GetKey()]++
 	}
 
 	// 测试Maglev哈希分布(65537表长)
@@ -109,7 +129,7 @@ func testDistribution() {
 			fmt.Printf("maglevHash65537 get err: %v\n", err)
 			return
 		}
-		maglevHash65537Distribution[node]++
+		maglevHash65537Distribution[node.GetKey()]++
 	}
 
 	// 测试AnchorHash分布(2000长度)
@@ -150,6 +170,7 @@ func testDistribution() {
 	ringHash40StdDev := calculateStdDev(ringHash40Distribution, avg, nodeCount)
 	ringHash160StdDev := calculateStdDev(ringHash160Distribution, avg, nodeCount)
 	rendezvousHashStdDev := calculateStdDev(rendezvousHashDistribution, avg, nodeCount)
+	hierarchicalRendezvousHashStdDev := calculateStdDev(hierarchicalRendezvousHashDistribution, avg, nodeCount)
 	jumpHashStdDev := calculateStdDev(jumpHashDistribution, avg, nodeCount)
 	maglevHash2039StdDev := calculateStdDev(maglevHash2039Distribution, avg, nodeCount)
 	maglevHash65537StdDev := calculateStdDev(maglevHash65537Distribution, avg, nodeCount)
@@ -163,6 +184,7 @@ func testDistribution() {
 	fmt.Printf("  RingHash(40个虚拟节点)标准差: %.2f\n", ringHash40StdDev)
 	fmt.Printf("  RingHash(160个虚拟节点)标准差: %.2f\n", ringHash160StdDev)
 	fmt.Printf("  RendezvousHash标准差: %.2f\n", rendezvousHashStdDev)
+	fmt.Printf("  HierarchicalRendezvousHash标准差: %.2f\n", hierarchicalRendezvousHashStdDev)
 	fmt.Printf("  JumpHash标准差: %.2f\n", jumpHashStdDev)
 	fmt.Printf("  MaglevHash(2039表长)标准差: %.2f\n", maglevHash2039StdDev)
 	fmt.Printf("  MaglevHash(65537表长)标准差: %.2f\n", maglevHash65537StdDev)
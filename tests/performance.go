@@ -33,11 +33,20 @@ func testPerformance() {
 	ringHash40 := ring_hash.NewRingHash(40, 1, nodeList, utils.GetHashCode) // 添加40个虚拟节点的测试
 	ringHash160 := ring_hash.NewRingHash(160, 1, nodeList, utils.GetHashCode)
 	rendezvousHash := rendezvous_hash.NewRendezvousHash(nodeList, utils.GetHashCode)
+	hierarchicalRendezvousHash := rendezvous_hash.NewHierarchicalRendezvousHash(nodeList, 8, utils.GetHashCode)
 	jumpHash := jump_hash.NewJumpHash(nodeList, utils.GetHashCode)
-	maglevHash2039 := maglev_hash.NewMaglevHash(nodeKeyList, 2039)
-	maglevHash65537 := maglev_hash.NewMaglevHash(nodeKeyList, 65537)
+	maglevHash2039, err := maglev_hash.NewMaglevHash(nodeList, 2039, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		fmt.Printf("maglevHash2039 new err: %v\n", err)
+		return
+	}
+	maglevHash65537, err := maglev_hash.NewMaglevHash(nodeList, 65537, utils.GetHashCode, utils.GetSecondaryHashCode)
+	if err != nil {
+		fmt.Printf("maglevHash65537 new err: %v\n", err)
+		return
+	}
 	anchorHash2000 := anchor_hash.NewAnchorHash(nodeKeyList, 2000, utils.GetHashCode)
-	dxHash := dx_hash.NewDxHash(nodeList, nodeCount)
+	dxHash := dx_hash.NewDxHash(nodeList, nodeCount, nil)
 	slotHash := slot_hash.NewSlotHash(nodeList, utils.GetHashCode)
 
 	// 生成测试键
@@ -67,6 +76,13 @@ func testPerformance() {
 	}
 	rhElapsed := time.Since(start)
 
+	// 测试层次化Rendezvous哈希性能
+	start = time.Now()
+	for _, key := range keys {
+		hierarchicalRendezvousHash.Get(key)
+	}
+	hrhElapsed := time.Since(start)
+
 	// 测试跳跃哈希性能
 	start = time.Now()
 	for _, key := range keys {
@@ -114,6 +130,7 @@ func testPerformance() {
 	fmt.Printf("  RingHash(40个虚拟节点): %v\n", rh40Elapsed)
 	fmt.Printf("  RingHash(160个虚拟节点): %v\n", rh160Elapsed)
 	fmt.Printf("  RendezvousHash: %v\n", rhElapsed)
+	fmt.Printf("  HierarchicalRendezvousHash: %v\n", hrhElapsed)
 	fmt.Printf("  JumpHash: %v\n", jhElapsed)
 	fmt.Printf("  MaglevHash(2039表长): %v\n", mh2039Elapsed)
 	fmt.Printf("  MaglevHash(65537表长): %v\n", mhElapsed)
@@ -1,12 +1,7 @@
 package tests
 
 import (
-	"consistent-hash/algorithms/anchor_hash"
-	"consistent-hash/algorithms/dx_hash"
-	"consistent-hash/algorithms/jump_hash"
-	"consistent-hash/algorithms/maglev_hash"
-	"consistent-hash/algorithms/rendezvous_hash"
-	"consistent-hash/algorithms/ring_hash"
+	"consistent-hash/algorithms"
 	"consistent-hash/algorithms/slot_hash"
 	"consistent-hash/models"
 	"consistent-hash/utils"
@@ -23,22 +18,43 @@ func testAddNodeRemapping() {
 
 	// 节点
 	nodeList := make([]models.HashNode, 0, initialNodes)
-	nodeKeyList := make([]string, 0, initialNodes)
 	for i := 0; i < initialNodes; i++ {
 		nodeKey := fmt.Sprintf("node_%d", i)
-		nodeKeyList = append(nodeKeyList, nodeKey)
 		nodeList = append(nodeList, models.NewNormalHashNode(nodeKey, 1, true))
 	}
 
 	// 创建各种算法实例
-	ringHash40 := ring_hash.NewRingHash(40, 1, nodeList, utils.GetHashCode) // 添加40个虚拟节点的测试
-	ringHash160 := ring_hash.NewRingHash(160, 1, nodeList, utils.GetHashCode)
-	rendezvousHash := rendezvous_hash.NewRendezvousHash(nodeList, utils.GetHashCode)
-	jumpHash := jump_hash.NewJumpHash(nodeList, utils.GetHashCode)
-	maglevHash2039 := maglev_hash.NewMaglevHash(nodeKeyList, 2039)
-	maglevHash65537 := maglev_hash.NewMaglevHash(nodeKeyList, 65537)
-	anchorHash2000 := anchor_hash.NewAnchorHash(nodeKeyList, 2000, utils.GetHashCode)
-	dxHash := dx_hash.NewDxHash(nodeList, initialNodes)
+	maglevHash2039, err := algorithms.NewMaglevHashBalancer(nodeList, &algorithms.MaglevHashOptions{TableSize: 2039})
+	if err != nil {
+		fmt.Printf("maglevHash2039 new err: %v\n", err)
+		return
+	}
+	maglevHash65537, err := algorithms.NewMaglevHashBalancer(nodeList, &algorithms.MaglevHashOptions{TableSize: 65537})
+	if err != nil {
+		fmt.Printf("maglevHash65537 new err: %v\n", err)
+		return
+	}
+	minimalMaglevHash65537, err := algorithms.NewMaglevHashBalancer(nodeList,
+		&algorithms.MaglevHashOptions{TableSize: 65537, MinimalDisruption: true})
+	if err != nil {
+		fmt.Printf("minimalMaglevHash65537 new err: %v\n", err)
+		return
+	}
+	balancers := []struct {
+		name     string // 输出中的名称
+		balancer algorithms.Balancer[models.HashNode]
+	}{
+		{"哈希环(40个虚拟节点)", algorithms.NewRingHashBalancer(40, 1, nodeList, utils.GetHashCode)},
+		{"哈希环(160个虚拟节点)", algorithms.NewRingHashBalancer(160, 1, nodeList, utils.GetHashCode)},
+		{"Rendezvous哈希", algorithms.NewRendezvousHashBalancer(nodeList, utils.GetHashCode)},
+		{"层次化Rendezvous哈希", algorithms.NewHierarchicalRendezvousHashBalancer(nodeList, 8, utils.GetHashCode)},
+		{"跳跃哈希", algorithms.NewJumpHashBalancer(nodeList, utils.GetHashCode)},
+		{"Maglev哈希(2039表长)", maglevHash2039},
+		{"Maglev哈希(65537表长)", maglevHash65537},
+		{"Maglev哈希(65537表长，低扰动重建)", minimalMaglevHash65537},
+		{"AnchorHash", algorithms.NewAnchorHashBalancer(nodeList, 2000, utils.GetHashCode)},
+		{"DxHash", algorithms.NewDxHashBalancer(nodeList, initialNodes, nil)},
+	}
 	slotHash := slot_hash.NewSlotHash(nodeList, utils.GetHashCode)
 
 	// 生成测试键
@@ -48,61 +64,19 @@ func testAddNodeRemapping() {
 	}
 	// 新增的节点
 	newNodeList := make([]models.HashNode, addCount)
-	newNodeKeyList := make([]string, addCount)
 	for i := 0; i < addCount; i++ {
 		nodeNum := initialNodes + i
-		nk := fmt.Sprintf("node_%d", nodeNum)
-		newNodeKeyList[i] = nk
-		newNodeList[i] = models.NewNormalHashNode(nk, 1, true)
+		newNodeList[i] = models.NewNormalHashNode(fmt.Sprintf("node_%d", nodeNum), 1, true)
 	}
 
-	// 测试哈希环(40个虚拟节点)
-	rh40Changed, rh40Elapsed, err := remappingOfRingHash40(ringHash40, addCount, keyCount, keys, newNodeList)
-	if err != nil {
-		fmt.Printf("remappingOfRingHash40 err: %v", err)
-		return
-	}
-	// 测试哈希环(160个虚拟节点)
-	rh160Changed, rh160Elapsed, err := remappingOfRingHash160(ringHash160, addCount, keyCount, keys, newNodeList)
-	if err != nil {
-		fmt.Printf("remappingOfRingHash160 err: %v", err)
-		return
-	}
-	// 测试Rendezvous哈希
-	rhChanged, rhElapsed, err := remappingOfRendezvousHash(rendezvousHash, addCount, keyCount, keys, newNodeList)
-	if err != nil {
-		fmt.Printf("remappingOfRendezvousHash err: %v", err)
-		return
-	}
-	// 测试跳跃哈希
-	jhChanged, jhElapsed, err := remappingOfJumpHash(jumpHash, addCount, keyCount, keys, newNodeList)
-	if err != nil {
-		fmt.Printf("remappingOfJumpHash err: %v", err)
-		return
-	}
-	// 测试Maglev哈希(2039表长)
-	mh2039Changed, mh2039Elapsed, err := remappingOfMaglevHash2039(maglevHash2039, addCount, keyCount, keys, newNodeKeyList)
-	if err != nil {
-		fmt.Printf("remappingOfMaglevHash2039 err: %v", err)
-		return
-	}
-	// 测试Maglev哈希(65537表长)
-	mh65537Changed, mh65537Elapsed, err := remappingOfMaglevHash65537(maglevHash65537, addCount, keyCount, keys, newNodeKeyList)
-	if err != nil {
-		fmt.Printf("remappingOfMaglevHash65537 err: %v", err)
-		return
-	}
-	// 测试AnchorHash
-	ahChanged, ahElapsed, err := remappingOfAnchorHash(anchorHash2000, addCount, keyCount, keys, newNodeKeyList)
-	if err != nil {
-		fmt.Printf("remappingOfAnchorHash err: %v", err)
-		return
-	}
-	// 测试DxHash
-	dhChanged, dhElapsed, err := remappingOfDxHash(dxHash, addCount, keyCount, keys, newNodeList)
-	if err != nil {
-		fmt.Printf("remappingOfDxHash err: %v", err)
-		return
+	changedList := make([]int, len(balancers))
+	elapsedList := make([]time.Duration, len(balancers))
+	for i, b := range balancers {
+		changedList[i], elapsedList[i], err = remappingOfBalancer(b.balancer, addCount, keys, newNodeList)
+		if err != nil {
+			fmt.Printf("%s remapping err: %v", b.name, err)
+			return
+		}
 	}
 	// 测试SlotHash
 	shChanged, shElapsed, err := remappingOfSlotHash(slotHash, addCount, keyCount, keys, newNodeList)
@@ -113,287 +87,39 @@ func testAddNodeRemapping() {
 
 	fmt.Println("=== 添加节点时的重映射测试 ===")
 	fmt.Printf("添加 %d 个节点到 %d 个初始节点:\n", addCount, initialNodes)
-	fmt.Printf("  哈希环(40个虚拟节点): 耗时 %v, 重映射键数 %d (%.2f%%)\n", rh40Elapsed, rh40Changed, float64(rh40Changed)*100/float64(keyCount))
-	fmt.Printf("  哈希环(160个虚拟节点): 耗时 %v, 重映射键数 %d (%.2f%%)\n", rh160Elapsed, rh160Changed, float64(rh160Changed)*100/float64(keyCount))
-	fmt.Printf("  Rendezvous哈希: 耗时 %v, 重映射键数 %d (%.2f%%)\n", rhElapsed, rhChanged, float64(rhChanged)*100/float64(keyCount))
-	fmt.Printf("  跳跃哈希: 耗时 %v, 重映射键数 %d (%.2f%%)\n", jhElapsed, jhChanged, float64(jhChanged)*100/float64(keyCount))
-	fmt.Printf("  Maglev哈希(2039表长): 耗时 %v, 重映射键数 %d (%.2f%%)\n", mh2039Elapsed, mh2039Changed, float64(mh2039Changed)*100/float64(keyCount))
-	fmt.Printf("  Maglev哈希(65537表长): 耗时 %v, 重映射键数 %d (%.2f%%)\n", mh65537Elapsed, mh65537Changed, float64(mh65537Changed)*100/float64(keyCount))
-	fmt.Printf("  AnchorHash: 耗时 %v, 重映射键数 %d (%.2f%%)\n", ahElapsed, ahChanged, float64(ahChanged)*100/float64(keyCount))
-	fmt.Printf("  DxHash: 耗时 %v, 重映射键数 %d (%.2f%%)\n", dhElapsed, dhChanged, float64(dhChanged)*100/float64(keyCount))
-	fmt.Printf("  SlotHash: 耗时 %v, 重映射键数 %d (%.2f%%)\n", shElapsed, shChanged, float64(shChanged)*100/float64(keyCount))
-}
-
-func remappingOfRingHash40[T models.HashNode](ringHash40 *ring_hash.RingHash[T],
-	addCount, keyCount int, keys []string, newNodeList []T) (int, time.Duration, error) {
-	var err error
-	// 测试哈希环(40个虚拟节点)
-	rh40Before := make([]T, keyCount)
-	for i, key := range keys {
-		var resultNodes []T
-		resultNodes, err = ringHash40.Get(key, 1)
-		if err != nil {
-			fmt.Printf("ringHash40 get err: %v\n", err)
-			return 0, 0, err
-		}
-		rh40Before[i] = resultNodes[0]
+	for i, b := range balancers {
+		fmt.Printf("  %s: 耗时 %v, 重映射键数 %d (%.2f%%)\n", b.name, elapsedList[i], changedList[i], float64(changedList[i])*100/float64(keyCount))
 	}
-
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		ringHash40.AddNode(newNodeList[i])
-	}
-	rh40Elapsed := time.Since(start)
-
-	rh40After := make([]T, keyCount)
-	rh40Changed := 0
-	for i, key := range keys {
-		var resultNodes []T
-		resultNodes, err = ringHash40.Get(key, 1)
-		if err != nil {
-			fmt.Printf("ringHash40 get err: %v\n", err)
-			return 0, 0, err
-		}
-		rh40After[i] = resultNodes[0]
-		if rh40Before[i].GetKey() != rh40After[i].GetKey() {
-			rh40Changed++
-		}
-	}
-	return rh40Changed, rh40Elapsed, nil
-}
-
-func remappingOfRingHash160[T models.HashNode](ringHash160 *ring_hash.RingHash[T],
-	addCount, keyCount int, keys []string, newNodeList []T) (int, time.Duration, error) {
-	var err error
-	rh160Before := make([]T, keyCount)
-	for i, key := range keys {
-		var resultNodes []T
-		resultNodes, err = ringHash160.Get(key, 1)
-		if err != nil {
-			fmt.Printf("ringHash160 get err: %v\n", err)
-			return 0, 0, err
-		}
-		rh160Before[i] = resultNodes[0]
-	}
-
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		ringHash160.AddNode(newNodeList[i])
-	}
-	rh160Elapsed := time.Since(start)
-
-	rh160After := make([]T, keyCount)
-	rh160Changed := 0
-	for i, key := range keys {
-		var resultNodes []T
-		resultNodes, err = ringHash160.Get(key, 1)
-		if err != nil {
-			fmt.Printf("ringHash160 get err: %v\n", err)
-			return 0, 0, err
-		}
-		rh160After[i] = resultNodes[0]
-		if rh160Before[i].GetKey() != rh160After[i].GetKey() {
-			rh160Changed++
-		}
-	}
-	return rh160Changed, rh160Elapsed, nil
-}
-
-func remappingOfRendezvousHash[T models.HashNode](rendezvousHash *rendezvous_hash.RendezvousHash[T],
-	addCount, keyCount int, keys []string, newNodeList []T) (int, time.Duration, error) {
-	var err error
-	rhBefore := make([]T, keyCount)
-	for i, key := range keys {
-		rhBefore[i], err = rendezvousHash.Get(key)
-		if err != nil {
-			fmt.Printf("rendezvousHash get err: %v\n", err)
-			return 0, 0, err
-		}
-	}
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		rendezvousHash.AddNode(newNodeList[i])
-	}
-	rhElapsed := time.Since(start)
-
-	rhAfter := make([]T, keyCount)
-	rhChanged := 0
-	for i, key := range keys {
-		rhAfter[i], err = rendezvousHash.Get(key)
-		if err != nil {
-			fmt.Printf("rendezvousHash get err: %v\n", err)
-			return 0, 0, err
-		}
-		if rhBefore[i].GetKey() != rhAfter[i].GetKey() {
-			rhChanged++
-		}
-	}
-	return rhChanged, rhElapsed, nil
-}
-
-func remappingOfJumpHash[T models.HashNode](jumpHash *jump_hash.JumpHash[T],
-	addCount, keyCount int, keys []string, newNodeList []T) (int, time.Duration, error) {
-	var err error
-	jhBefore := make([]T, keyCount)
-	for i, key := range keys {
-		jhBefore[i], err = jumpHash.Get(key)
-		if err != nil {
-			fmt.Printf("jumpHash get err: %v\n", err)
-			return 0, 0, err
-		}
-	}
-
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		jumpHash.AddNode(newNodeList[i])
-	}
-	jhElapsed := time.Since(start)
-
-	jhAfter := make([]T, keyCount)
-	jhChanged := 0
-	for i, key := range keys {
-		jhAfter[i], err = jumpHash.Get(key)
-		if err != nil {
-			fmt.Printf("jumpHash get err: %v\n", err)
-			return 0, 0, err
-		}
-		if jhBefore[i].GetKey() != jhAfter[i].GetKey() {
-			jhChanged++
-		}
-	}
-	return jhChanged, jhElapsed, nil
-}
-
-func remappingOfMaglevHash2039(maglevHash2039 *maglev_hash.MaglevHash,
-	addCount, keyCount int, keys []string, newNodeKeyList []string) (int, time.Duration, error) {
-	var err error
-	mh2039Before := make([]string, keyCount)
-	for i, key := range keys {
-		mh2039Before[i], err = maglevHash2039.Get(key)
-		if err != nil {
-			fmt.Printf("maglevHash2039 get err: %v\n", err)
-			return 0, 0, err
-		}
-	}
-
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		maglevHash2039.AddNode(newNodeKeyList[i])
-	}
-	mh2039Elapsed := time.Since(start)
-
-	mh2039After := make([]string, keyCount)
-	mh2039Changed := 0
-	for i, key := range keys {
-		mh2039After[i], err = maglevHash2039.Get(key)
-		if err != nil {
-			fmt.Printf("maglevHash2039 get err: %v\n", err)
-			return 0, 0, err
-		}
-		if mh2039Before[i] != mh2039After[i] {
-			mh2039Changed++
-		}
-	}
-	return mh2039Changed, mh2039Elapsed, nil
-}
-
-func remappingOfMaglevHash65537(maglevHash65537 *maglev_hash.MaglevHash,
-	addCount, keyCount int, keys []string, newNodeKeyList []string) (int, time.Duration, error) {
-	var err error
-	mhBefore := make([]string, keyCount)
-	for i, key := range keys {
-		mhBefore[i], err = maglevHash65537.Get(key)
-		if err != nil {
-			fmt.Printf("maglevHash65537 get err: %v\n", err)
-			return 0, 0, err
-		}
-	}
-
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		maglevHash65537.AddNode(newNodeKeyList[i])
-	}
-	mhElapsed := time.Since(start)
-
-	mhAfter := make([]string, keyCount)
-	mhChanged := 0
-	for i, key := range keys {
-		mhAfter[i], err = maglevHash65537.Get(key)
-		if err != nil {
-			fmt.Printf("maglevHash65537 get err: %v\n", err)
-			return 0, 0, err
-		}
-		if mhBefore[i] != mhAfter[i] {
-			mhChanged++
-		}
-	}
-	return mhChanged, mhElapsed, nil
+	fmt.Printf("  SlotHash: 耗时 %v, 重映射键数 %d (%.2f%%)\n", shElapsed, shChanged, float64(shChanged)*100/float64(keyCount))
 }
 
-func remappingOfAnchorHash(anchorHash2000 *anchor_hash.AnchorHash,
-	addCount, keyCount int, keys []string, newNodeKeyList []string) (int, time.Duration, error) {
-	var err error
-	ahBefore := make([]string, keyCount)
-	for i, key := range keys {
-		ahBefore[i], err = anchorHash2000.Get(key)
-		if err != nil {
-			fmt.Printf("anchorHash2000 get err: %v\n", err)
-			return 0, 0, err
-		}
-	}
+// remappingOfBalancer 添加节点前后并发查询所有key，返回映射发生变化的key数量及添加节点的耗时
+func remappingOfBalancer[T models.HashNode](b algorithms.Balancer[T],
+	addCount int, keys []string, newNodeList []T) (int, time.Duration, error) {
+	before := algorithms.GetMany(b, keys, 0)
 
 	start := time.Now()
 	for i := 0; i < addCount; i++ {
-		anchorHash2000.AddBucket(newNodeKeyList[i])
-	}
-	ahElapsed := time.Since(start)
-
-	ahAfter := make([]string, keyCount)
-	ahChanged := 0
-	for i, key := range keys {
-		ahAfter[i], err = anchorHash2000.Get(key)
-		if err != nil {
-			fmt.Printf("anchorHash2000 get err: %v\n", err)
+		if err := b.AddNode(newNodeList[i]); err != nil {
 			return 0, 0, err
 		}
-		if ahBefore[i] != ahAfter[i] {
-			ahChanged++
-		}
 	}
-	return ahChanged, ahElapsed, nil
-}
+	elapsed := time.Since(start)
 
-func remappingOfDxHash[T models.HashNode](dxHash *dx_hash.DxHash[T],
-	addCount, keyCount int, keys []string, newNodeList []T) (int, time.Duration, error) {
-	var err error
-	dxBefore := make([]T, keyCount)
-	for i, key := range keys {
-		dxBefore[i], err = dxHash.Get(key)
-		if err != nil {
-			fmt.Printf("dxHash get err: %v\n", err)
-			return 0, 0, err
+	after := algorithms.GetMany(b, keys, 0)
+	changed := 0
+	for i := range keys {
+		if before[i].Err != nil {
+			return 0, 0, before[i].Err
 		}
-	}
-
-	start := time.Now()
-	for i := 0; i < addCount; i++ {
-		dxHash.AddNode(newNodeList[i])
-	}
-	dxElapsed := time.Since(start)
-
-	dxAfter := make([]T, keyCount)
-	dxChanged := 0
-	for i, key := range keys {
-		dxAfter[i], err = dxHash.Get(key)
-		if err != nil {
-			fmt.Printf("dxHash get err: %v\n", err)
-			return 0, 0, err
+		if after[i].Err != nil {
+			return 0, 0, after[i].Err
 		}
-		if dxBefore[i].GetKey() != dxAfter[i].GetKey() {
-			dxChanged++
+		if before[i].Node.GetKey() != after[i].Node.GetKey() {
+			changed++
 		}
 	}
-	return dxChanged, dxElapsed, nil
+	return changed, elapsed, nil
 }
 
 func remappingOfSlotHash[T models.HashNode](shHash *slot_hash.SlotHash[T],
@@ -0,0 +1,8 @@
+package utils
+
+import "unsafe"
+
+// StringToBytes 零拷贝地将字符串转换为字节切片，返回的切片不能被修改
+func StringToBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
@@ -1,9 +1,41 @@
 package utils
 
-import "github.com/spaolacci/murmur3"
+import (
+	"math"
 
-const DefaultHashSeedNum = 192
+	"github.com/spaolacci/murmur3"
+)
+
+const (
+	DefaultHashSeedNum   = 192
+	SecondaryHashSeedNum = 1031 // 与默认种子不同，得到与 GetHashCode 相互独立的哈希
+)
 
 func GetHashCode(key []byte) uint64 {
 	return murmur3.Sum64WithSeed(key, DefaultHashSeedNum)
 }
+
+// GetSecondaryHashCode 与 GetHashCode 相互独立的哈希函数，适用于需要两个哈希的算法，如 Maglev 的偏移量和跳跃步长
+func GetSecondaryHashCode(key []byte) uint64 {
+	return murmur3.Sum64WithSeed(key, SecondaryHashSeedNum)
+}
+
+// Mix64 splitmix64 的混淆函数，将相近的输入打散为均匀分布的64位哈希
+func Mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// WeightedHashScore 加权 rendezvous 得分 weight / -ln(u)，u 为哈希映射到 (0, 1) 的均匀值。
+// 得分最高的节点被选中的概率与其权重成正比，权重不大于0时得分为0
+func WeightedHashScore(hash uint64, weight int) float64 {
+	if weight <= 0 {
+		return 0
+	}
+	u := (float64(hash>>11) + 0.5) / (1 << 53)
+	return float64(weight) / -math.Log(u)
+}